@@ -232,6 +232,8 @@ For more details read: https://parse.com/docs/cloudcode/guide#command-line-webho
 	hooksCmd.Flags().StringVarP(&c.hooks.BaseURL, "base", "b", c.hooks.BaseURL,
 		`Base url to use while parsing the webhook url field.
 If provided, the config file can have relative urls.`)
+	hooksCmd.Flags().BoolVar(&c.hooks.Confirmed, parsecli.ConfirmProtectedFlag, c.hooks.Confirmed,
+		"Configure webhooks of protected apps without asking for confirmation")
 	cmd.AddCommand(hooksCmd)
 
 	return cmd
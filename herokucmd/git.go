@@ -21,13 +21,7 @@ type gitInfo struct {
 }
 
 func (g *gitInfo) whichGit() error {
-	_, err := exec.LookPath("git")
-	if err != nil {
-		return stackerr.New(`Unable to locate "git".
-Please install "git" and ensure that you are able to run "git help" from the command prompt.`,
-		)
-	}
-	return nil
+	return parsecli.LookGit()
 }
 
 func (g *gitInfo) isGitRepo(e *parsecli.Env) error {
@@ -162,11 +156,8 @@ func (g *gitInfo) pull(e *parsecli.Env, gitURL string) error {
 }
 
 func (g *gitInfo) clone(gitURL, path string) error {
-	_, err := exec.LookPath("git")
-	if err != nil {
-		return stackerr.New(`Unable to locate "git".
-Please install "git" and ensure that you are able to run "git help" from the command prompt.`,
-		)
+	if err := g.whichGit(); err != nil {
+		return err
 	}
 
 	cmd := exec.Command("git", "clone", gitURL, path)
@@ -36,6 +36,7 @@ func main() {
 		Exit:        os.Exit,
 		Clock:       clock.New(),
 	}
+	e.Progress = parsecli.NewProgressReporter(e.Err, e.Clock)
 	if e.Root == "" {
 		cur, err := os.Getwd()
 		if err != nil {
@@ -32,6 +32,7 @@ http://parse.com`,
 	c.AddCommand(NewDefaultCmd(e))
 	c.AddCommand(parsecmd.NewDeployCmd(e))
 	c.AddCommand(parsecmd.NewDevelopCmd(e))
+	c.AddCommand(parsecmd.NewDiffCmd(e))
 	c.AddCommand(parsecmd.NewDownloadCmd(e))
 	c.AddCommand(webhooks.NewFunctionHooksCmd(e))
 	c.AddCommand(parsecmd.NewGenerateCmd(e))
@@ -42,6 +43,7 @@ http://parse.com`,
 	c.AddCommand(NewNewCmd(e))
 	c.AddCommand(parsecmd.NewReleasesCmd(e))
 	c.AddCommand(parsecmd.NewRollbackCmd(e))
+	c.AddCommand(parsecmd.NewStatusCmd(e))
 	c.AddCommand(parsecmd.NewSymbolsCmd(e))
 	c.AddCommand(webhooks.NewTriggerHooksCmd(e))
 	c.AddCommand(NewUpdateCmd(e))
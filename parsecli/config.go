@@ -25,6 +25,9 @@ type AppConfig interface {
 	GetMasterKey(e *Env) (string, error)
 	GetApplicationAuth(e *Env) (string, error)
 	GetLink() string
+	// IsProtected reports whether commands changing the app must be
+	// confirmed.
+	IsProtected() bool
 }
 
 type Config interface {
@@ -35,6 +38,9 @@ type Config interface {
 	GetProjectConfig() *ProjectConfig
 	GetDefaultApp() string
 	GetNumApps() int
+	// GetAppNames returns the sorted names of the configured apps, without
+	// aliases.
+	GetAppNames() []string
 	PrettyPrintApps(*Env)
 }
 
@@ -13,6 +13,25 @@ type Context struct {
 	AppConfig AppConfig
 }
 
+// NewAppContext returns a copy of e with its own API clients, along with the
+// context of the given app, so that a command can run against several apps at
+// once.
+func NewAppContext(e *Env, appName string) (*Env, *Context, error) {
+	ae := *e
+	if e.ParseAPIClient != nil {
+		ae.ParseAPIClient = &ParseAPIClient{APIClient: e.ParseAPIClient.APIClient}
+	}
+	if e.HerokuAPIClient != nil {
+		client := *e.HerokuAPIClient
+		ae.HerokuAPIClient = &client
+	}
+	c, err := newContext(&ae, appName)
+	if err != nil {
+		return nil, nil, err
+	}
+	return &ae, c, nil
+}
+
 func newContext(e *Env, appName string) (*Context, error) {
 	config, err := ConfigFromDir(e.Root)
 	if err != nil {
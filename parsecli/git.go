@@ -0,0 +1,107 @@
+package parsecli
+
+import (
+	"bytes"
+	"fmt"
+	"os/exec"
+	"regexp"
+	"strings"
+
+	"github.com/facebookgo/stackerr"
+)
+
+// LookGit returns an error explaining how to install git if it is not found.
+func LookGit() error {
+	if _, err := exec.LookPath("git"); err != nil {
+		return stackerr.New(`Unable to locate "git".
+Please install "git" and ensure that you are able to run "git help" from the command prompt.`,
+		)
+	}
+	return nil
+}
+
+// GitCommit describes the commit checked out in a git repository.
+type GitCommit struct {
+	SHA    string
+	Branch string // "HEAD" when no branch is checked out
+	Dirty  bool   // set when the work tree has uncommitted changes
+}
+
+// gitOutput runs git in dir and returns its trimmed output.
+func gitOutput(dir string, args ...string) (string, error) {
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	var out bytes.Buffer
+	cmd.Stdout = &out
+	if err := cmd.Run(); err != nil {
+		return "", stackerr.Wrap(err)
+	}
+	return strings.TrimSpace(out.String()), nil
+}
+
+// GitHeadCommit returns the commit checked out in the git repository
+// containing dir. It returns nil if git is not installed, if dir is not in a
+// git repository, or if the repository has no commits yet.
+func GitHeadCommit(dir string) *GitCommit {
+	if LookGit() != nil {
+		return nil
+	}
+	sha, err := gitOutput(dir, "rev-parse", "HEAD")
+	if err != nil {
+		return nil
+	}
+	branch, err := gitOutput(dir, "rev-parse", "--abbrev-ref", "HEAD")
+	if err != nil {
+		return nil
+	}
+	status, err := gitOutput(dir, "status", "--porcelain")
+	if err != nil {
+		return nil
+	}
+	return &GitCommit{SHA: sha, Branch: branch, Dirty: status != ""}
+}
+
+// gitTagRegexp matches the tag added by DescribeWithCommit at the end of a
+// description. SHAs may be abbreviated, or be SHA-256 object names.
+var gitTagRegexp = regexp.MustCompile(`(^|\s)\[git ([0-9a-f]{7,64}) (\S+)( dirty)?\]$`)
+
+// DescribeWithCommit appends the commit to a release description, in a form
+// ParseCommitDescription reads back.
+func DescribeWithCommit(description string, c *GitCommit) string {
+	if c == nil {
+		return description
+	}
+	tag := fmt.Sprintf("[git %s %s]", c.SHA, c.Branch)
+	if c.Dirty {
+		tag = fmt.Sprintf("[git %s %s dirty]", c.SHA, c.Branch)
+	}
+	if description == "" {
+		return tag
+	}
+	return description + " " + tag
+}
+
+// ParseCommitDescription splits a release description written by
+// DescribeWithCommit into the description given by the user and the commit.
+// The commit is nil if the description does not name one.
+func ParseCommitDescription(description string) (string, *GitCommit) {
+	m := gitTagRegexp.FindStringSubmatchIndex(description)
+	if m == nil {
+		return description, nil
+	}
+	c := &GitCommit{
+		SHA:    description[m[4]:m[5]],
+		Branch: description[m[6]:m[7]],
+		Dirty:  m[8] != -1,
+	}
+	return strings.TrimSpace(description[:m[0]]), c
+}
+
+// String returns the short SHA and branch of the commit.
+func (c *GitCommit) String() string {
+	s := fmt.Sprintf("%s (%s)", c.SHA[:7], c.Branch)
+	if c.Dirty {
+		s = fmt.Sprintf("%s (%s, dirty)", c.SHA[:7], c.Branch)
+	}
+	return s
+}
@@ -0,0 +1,92 @@
+package parsecli
+
+import (
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/facebookgo/ensure"
+)
+
+const testSHA = "0123456789abcdef0123456789abcdef01234567"
+
+func TestCommitDescriptionRoundTrip(t *testing.T) {
+	t.Parallel()
+	commit := &GitCommit{SHA: testSHA, Branch: "main", Dirty: true}
+	for _, description := range []string{"", "fix login", "ends with [brackets]"} {
+		d, c := ParseCommitDescription(DescribeWithCommit(description, commit))
+		ensure.DeepEqual(t, d, description)
+		ensure.DeepEqual(t, c, commit)
+	}
+
+	ensure.DeepEqual(t, DescribeWithCommit("fix login", &GitCommit{SHA: testSHA, Branch: "main"}),
+		"fix login [git "+testSHA+" main]")
+	ensure.DeepEqual(t, DescribeWithCommit("fix login", nil), "fix login")
+}
+
+func TestParseCommitDescriptionSHALengths(t *testing.T) {
+	t.Parallel()
+	sha256 := strings.Repeat("0123456789abcdef", 4)
+	for _, sha := range []string{"0123456", sha256} {
+		d, c := ParseCommitDescription("fix login [git " + sha + " main]")
+		ensure.DeepEqual(t, d, "fix login")
+		ensure.DeepEqual(t, c, &GitCommit{SHA: sha, Branch: "main"})
+	}
+}
+
+func TestParseCommitDescriptionWithoutCommit(t *testing.T) {
+	t.Parallel()
+	for _, description := range []string{"", "fix login", "[git abc main]", "x[git " + testSHA + " main]"} {
+		d, c := ParseCommitDescription(description)
+		ensure.DeepEqual(t, d, description)
+		ensure.True(t, c == nil)
+	}
+}
+
+func TestGitCommitString(t *testing.T) {
+	t.Parallel()
+	ensure.DeepEqual(t, (&GitCommit{SHA: testSHA, Branch: "main"}).String(), "0123456 (main)")
+	ensure.DeepEqual(t, (&GitCommit{SHA: testSHA, Branch: "main", Dirty: true}).String(),
+		"0123456 (main, dirty)")
+}
+
+func TestGitHeadCommit(t *testing.T) {
+	t.Parallel()
+	if LookGit() != nil {
+		t.Skip("git is not installed")
+	}
+	dir, err := ioutil.TempDir("", "git_")
+	ensure.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	ensure.True(t, GitHeadCommit(dir) == nil)
+
+	git := func(args ...string) {
+		cmd := exec.Command("git", append([]string{
+			"-c", "user.name=parse", "-c", "user.email=parse@example.com",
+		}, args...)...)
+		cmd.Dir = dir
+		out, err := cmd.CombinedOutput()
+		ensure.Nil(t, err, string(out))
+	}
+	git("init", "-q")
+	git("checkout", "-q", "-b", "release")
+	ensure.Nil(t, ioutil.WriteFile(filepath.Join(dir, "main.js"), []byte("content"), 0600))
+	git("add", "main.js")
+	git("commit", "-q", "-m", "initial")
+
+	sub := filepath.Join(dir, "cloud")
+	ensure.Nil(t, os.Mkdir(sub, 0755))
+	commit := GitHeadCommit(sub)
+	ensure.NotNil(t, commit)
+	ensure.DeepEqual(t, len(commit.SHA), 40)
+	ensure.DeepEqual(t, strings.Trim(commit.SHA, "0123456789abcdef"), "")
+	ensure.DeepEqual(t, commit.Branch, "release")
+	ensure.False(t, commit.Dirty)
+
+	ensure.Nil(t, ioutil.WriteFile(filepath.Join(dir, "main.js"), []byte("changed"), 0600))
+	ensure.True(t, GitHeadCommit(dir).Dirty)
+}
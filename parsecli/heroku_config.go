@@ -19,6 +19,8 @@ type HerokuAppConfig struct {
 	HerokuAppID       string `json:"herokuAppId,omitempty"`
 	HerokuAccessToken string `json:"herokuAccessToken,omitempty"`
 	Link              string `json:"link,omitempty"`
+	// Protected apps ask for confirmation before deploys and rollbacks.
+	Protected bool `json:"protected,omitempty"`
 
 	masterKey         string
 	herokuAccessToken string
@@ -99,6 +101,10 @@ func (c *HerokuAppConfig) GetLink() string {
 	return c.Link
 }
 
+func (c *HerokuAppConfig) IsProtected() bool {
+	return c.Protected
+}
+
 type HerokuConfig struct {
 	Applications  map[string]*HerokuAppConfig `json:"applications,omityempty"`
 	ProjectConfig *ProjectConfig              `json:"-"`
@@ -150,6 +156,17 @@ func (c *HerokuConfig) GetNumApps() int {
 	return len(c.Applications)
 }
 
+func (c *HerokuConfig) GetAppNames() []string {
+	var names []string
+	for name, app := range c.Applications {
+		if name != DefaultKey && app.Link == "" {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
 var herokuAppNotFoundRegex = regexp.MustCompile("App not found")
 
 func HerokuAppNotFound(err error) bool {
@@ -25,6 +25,7 @@ const (
 	Version        = "3.0.5"
 	CloudDir       = "cloud"
 	HostingDir     = "public"
+	StateDir       = ".parse"
 	DefaultBaseURL = "https://api.parse.com/1/"
 )
 
@@ -43,6 +44,7 @@ type Env struct {
 	Clock           clock.Clock
 	ParseAPIClient  *ParseAPIClient
 	HerokuAPIClient *heroku.Client
+	Progress        *ProgressReporter // reports file transfers, nil to report nothing
 }
 
 type Harness struct {
@@ -11,12 +11,42 @@ import (
 
 type ParseProjectConfig struct {
 	JSSDK string `json:"jssdk,omitempty"`
+	// Sources lists the directories deployed to Parse. The cloud and public
+	// directories are deployed when it is empty.
+	Sources []*SourceConfig `json:"sources,omitempty"`
+	// Hooks lists the commands run around deploys.
+	Hooks *HooksConfig `json:"hooks,omitempty"`
+}
+
+// HooksConfig lists shell commands run in the project root by deploy and
+// develop.
+type HooksConfig struct {
+	// Predeploy commands run before any file is uploaded. A failing command
+	// aborts the deploy.
+	Predeploy []string `json:"predeploy,omitempty"`
+	// Postdeploy commands run after a new release is created.
+	Postdeploy []string `json:"postdeploy,omitempty"`
+}
+
+// SourceConfig maps a directory of the project to the kind of files it is
+// deployed as.
+type SourceConfig struct {
+	// Dir is the directory relative to the project root.
+	Dir string `json:"dir"`
+	// Suffixes lists the file extensions to deploy, every file is deployed when
+	// it is empty.
+	Suffixes []string `json:"suffixes,omitempty"`
+	// Target is CloudDir to deploy Cloud Code scripts or HostingDir to deploy
+	// hosted files.
+	Target string `json:"target"`
 }
 
 type ParseAppConfig struct {
 	ApplicationID string `json:"applicationId,omitempty"`
 	MasterKey     string `json:"masterKey,omitempty"`
 	Link          string `json:"link,omitempty"`
+	// Protected apps ask for confirmation before deploys and rollbacks.
+	Protected bool `json:"protected,omitempty"`
 
 	masterKey string
 }
@@ -53,6 +83,10 @@ func (c *ParseAppConfig) GetLink() string {
 	return c.Link
 }
 
+func (c *ParseAppConfig) IsProtected() bool {
+	return c.Protected
+}
+
 type ParseConfig struct {
 	Applications  map[string]*ParseAppConfig `json:"applications,omitempty"`
 	ProjectConfig *ProjectConfig             `json:"-"`
@@ -104,6 +138,17 @@ func (c *ParseConfig) GetNumApps() int {
 	return len(c.Applications)
 }
 
+func (c *ParseConfig) GetAppNames() []string {
+	var names []string
+	for name, app := range c.Applications {
+		if name != DefaultKey && app.Link == "" {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
 func (c *ParseConfig) PrettyPrintApps(e *Env) {
 	apps := c.Applications
 
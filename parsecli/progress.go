@@ -0,0 +1,207 @@
+package parsecli
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/facebookgo/clock"
+)
+
+const (
+	// ttyProgressInterval is how often the progress line is redrawn on a
+	// terminal.
+	ttyProgressInterval = 100 * time.Millisecond
+	// plainProgressInterval is how often a progress line is printed when the
+	// output is not a terminal, so that CI logs are not flooded.
+	plainProgressInterval = 10 * time.Second
+)
+
+// ProgressReporter reports the progress of file transfers. A nil
+// ProgressReporter reports nothing.
+type ProgressReporter struct {
+	Out   io.Writer
+	Clock clock.Clock
+	// TTY redraws a single progress line in place instead of printing a new
+	// line every Interval.
+	TTY      bool
+	Interval time.Duration
+}
+
+// NewProgressReporter returns a reporter writing to out, which redraws its
+// progress line in place when out is a terminal.
+func NewProgressReporter(out io.Writer, c clock.Clock) *ProgressReporter {
+	p := &ProgressReporter{Out: out, Clock: c, Interval: plainProgressInterval}
+	if f, ok := out.(*os.File); ok {
+		if info, err := f.Stat(); err == nil && info.Mode()&os.ModeCharDevice != 0 {
+			p.TTY = true
+			p.Interval = ttyProgressInterval
+		}
+	}
+	return p
+}
+
+// Start starts reporting a transfer of the given number of files. The total
+// size may be 0 if it is not known in advance.
+func (p *ProgressReporter) Start(action string, files int, size int64) *Transfer {
+	if p == nil {
+		return nil
+	}
+	now := p.Clock.Now()
+	return &Transfer{
+		reporter: p,
+		action:   action,
+		files:    files,
+		size:     size,
+		start:    now,
+		reported: now,
+	}
+}
+
+// Transfer is the progress of a single transfer of files. All its methods
+// are safe for concurrent use, and do nothing on a nil Transfer.
+type Transfer struct {
+	reporter *ProgressReporter
+	action   string
+	files    int
+	size     int64
+	start    time.Time
+
+	mutex       sync.Mutex
+	done        int
+	transferred int64
+	reported    time.Time
+	width       int
+}
+
+// Add records n more bytes transferred, n is negative when bytes have to be
+// transferred again.
+func (t *Transfer) Add(n int64) {
+	if t == nil {
+		return
+	}
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	t.transferred += n
+	t.report(false)
+}
+
+// FileDone records that one more file was transferred.
+func (t *Transfer) FileDone() {
+	if t == nil {
+		return
+	}
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	t.done++
+	t.report(false)
+}
+
+// Finish prints the final progress of the transfer.
+func (t *Transfer) Finish() {
+	if t == nil {
+		return
+	}
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	t.report(true)
+	if t.reporter.TTY {
+		fmt.Fprintln(t.reporter.Out)
+	}
+}
+
+// Reader returns a reader recording the bytes read from r as transferred.
+func (t *Transfer) Reader(r io.Reader) *ProgressReader {
+	return &ProgressReader{Reader: r, transfer: t}
+}
+
+// ProgressReader records the bytes read through it in a Transfer.
+type ProgressReader struct {
+	io.Reader
+	transfer *Transfer
+	count    int64
+}
+
+func (r *ProgressReader) Read(p []byte) (int, error) {
+	n, err := r.Reader.Read(p)
+	atomic.AddInt64(&r.count, int64(n))
+	r.transfer.Add(int64(n))
+	return n, err
+}
+
+// Undo removes the bytes read so far from the transfer, for a file that has to
+// be transferred again.
+func (r *ProgressReader) Undo() {
+	r.transfer.Add(-atomic.SwapInt64(&r.count, 0))
+}
+
+// report prints the progress if it was not printed during the last interval,
+// or if force is set. It must be called with the mutex held.
+func (t *Transfer) report(force bool) {
+	now := t.reporter.Clock.Now()
+	if !force && now.Sub(t.reported) < t.reporter.Interval {
+		return
+	}
+	t.reported = now
+
+	line := t.line(now.Sub(t.start))
+	if !t.reporter.TTY {
+		fmt.Fprintln(t.reporter.Out, line)
+		return
+	}
+	// pad with spaces to erase the end of a longer previous line
+	padding := t.width - len(line)
+	if padding < 0 {
+		padding = 0
+	}
+	t.width = len(line)
+	fmt.Fprintf(t.reporter.Out, "\r%s%s", line, strings.Repeat(" ", padding))
+}
+
+func (t *Transfer) line(elapsed time.Duration) string {
+	line := fmt.Sprintf("%s: %d/%d files", t.action, t.done, t.files)
+	if t.size > 0 {
+		line += fmt.Sprintf(", %s/%s", FormatBytes(t.transferred), FormatBytes(t.size))
+	} else {
+		line += ", " + FormatBytes(t.transferred)
+	}
+	if elapsed <= 0 {
+		return line
+	}
+	rate := float64(t.transferred) / elapsed.Seconds()
+	line += fmt.Sprintf(", %s/s", FormatBytes(int64(rate)))
+
+	var eta time.Duration
+	switch {
+	case t.done == t.files:
+		return line
+	case t.size > 0 && rate > 0:
+		eta = time.Duration(float64(t.size-t.transferred) / rate * float64(time.Second))
+	case t.done > 0:
+		eta = elapsed * time.Duration(t.files-t.done) / time.Duration(t.done)
+	default:
+		return line
+	}
+	if eta < 0 {
+		eta = 0
+	}
+	return line + fmt.Sprintf(", ETA %s", eta/time.Second*time.Second)
+}
+
+// FormatBytes formats a number of bytes for humans.
+func FormatBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit && exp < 3; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %cB", float64(n)/float64(div), "KMGT"[exp])
+}
@@ -0,0 +1,95 @@
+package parsecli
+
+import (
+	"bytes"
+	"io/ioutil"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/facebookgo/clock"
+	"github.com/facebookgo/ensure"
+)
+
+func TestProgressPlain(t *testing.T) {
+	t.Parallel()
+	var out bytes.Buffer
+	c := clock.NewMock()
+	p := &ProgressReporter{Out: &out, Clock: c, Interval: 10 * time.Second}
+
+	transfer := p.Start("Uploading", 2, 2048)
+	transfer.Add(1024)
+	ensure.DeepEqual(t, out.String(), "")
+
+	c.Add(10 * time.Second)
+	transfer.FileDone()
+	ensure.DeepEqual(t, out.String(),
+		"Uploading: 1/2 files, 1.0 KB/2.0 KB, 102 B/s, ETA 10s\n")
+
+	out.Reset()
+	transfer.Add(1024)
+	transfer.FileDone()
+	ensure.DeepEqual(t, out.String(), "")
+	transfer.Finish()
+	ensure.DeepEqual(t, out.String(), "Uploading: 2/2 files, 2.0 KB/2.0 KB, 204 B/s\n")
+}
+
+func TestProgressTTY(t *testing.T) {
+	t.Parallel()
+	var out bytes.Buffer
+	c := clock.NewMock()
+	p := &ProgressReporter{Out: &out, Clock: c, TTY: true, Interval: time.Second}
+
+	transfer := p.Start("Downloading", 4, 0)
+	c.Add(2 * time.Second)
+	transfer.Add(4096)
+	c.Add(2 * time.Second)
+	transfer.FileDone()
+	transfer.Finish()
+	ensure.DeepEqual(t, out.String(),
+		"\rDownloading: 0/4 files, 4.0 KB, 2.0 KB/s"+
+			"\rDownloading: 1/4 files, 4.0 KB, 1.0 KB/s, ETA 12s"+
+			"\rDownloading: 1/4 files, 4.0 KB, 1.0 KB/s, ETA 12s\n")
+}
+
+func TestProgressReaderUndo(t *testing.T) {
+	t.Parallel()
+	var out bytes.Buffer
+	p := &ProgressReporter{Out: &out, Clock: clock.NewMock()}
+
+	transfer := p.Start("Uploading", 1, 5)
+	r := transfer.Reader(strings.NewReader("hello"))
+	_, err := ioutil.ReadAll(r)
+	ensure.Nil(t, err)
+	ensure.DeepEqual(t, transfer.transferred, int64(5))
+	r.Undo()
+	ensure.DeepEqual(t, transfer.transferred, int64(0))
+}
+
+func TestProgressNil(t *testing.T) {
+	t.Parallel()
+	var p *ProgressReporter
+	transfer := p.Start("Uploading", 1, 5)
+	ensure.True(t, transfer == nil)
+	_, err := ioutil.ReadAll(transfer.Reader(strings.NewReader("hello")))
+	ensure.Nil(t, err)
+	transfer.FileDone()
+	transfer.Finish()
+}
+
+func TestFormatBytes(t *testing.T) {
+	t.Parallel()
+	cases := map[int64]string{
+		0:                "0 B",
+		1023:             "1023 B",
+		1024:             "1.0 KB",
+		1536:             "1.5 KB",
+		5 * 1024 * 1024:  "5.0 MB",
+		3 << 30:          "3.0 GB",
+		2 << 40:          "2.0 TB",
+		2048 * (1 << 40): "2048.0 TB",
+	}
+	for n, expected := range cases {
+		ensure.DeepEqual(t, FormatBytes(n), expected)
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"strings"
 
+	"github.com/facebookgo/stackerr"
 	"github.com/spf13/cobra"
 )
 
@@ -113,6 +114,39 @@ or press ENTER to use the default app %q: `,
 	}
 }
 
+// ConfirmProtectedFlag is the flag skipping the confirmation of commands run
+// against protected apps, for use in CI.
+const ConfirmProtectedFlag = "yes-i-mean-production"
+
+// ConfirmProtectedApp asks to type the name of the app of the context before
+// running the given action against it, if the app is protected. Nothing is
+// asked when confirmed is set by ConfirmProtectedFlag.
+func ConfirmProtectedApp(e *Env, c *Context, action string, confirmed bool) error {
+	if confirmed || c == nil || c.AppConfig == nil || !c.AppConfig.IsProtected() {
+		return nil
+	}
+	appName := c.AppName
+	if appName == DefaultKey {
+		appName = c.Config.GetDefaultApp()
+	}
+
+	fmt.Fprintf(
+		e.Out,
+		`App %q is protected.
+Please type the name of the app to confirm the %s,
+or use --%s to skip this confirmation: `,
+		appName,
+		action,
+		ConfirmProtectedFlag,
+	)
+	var typed string
+	fmt.Fscanf(e.In, "%s\n", &typed)
+	if strings.TrimSpace(typed) != appName {
+		return stackerr.Newf("The %s was not confirmed, nothing was changed on app %q.", action, appName)
+	}
+	return nil
+}
+
 // RunWithArgsClient wraps a run function that should get an app, whee the default is
 // picked from the config in the current working directory. It also passes args to the
 // runner function
@@ -134,3 +168,35 @@ func RunWithArgsClient(e *Env, f func(*Env, *Context, []string) error) cobraRun
 		}
 	}
 }
+
+// RunWithClientArgs wraps a run function that expects exactly n arguments,
+// optionally followed by an app name. When no app name is given the default is
+// picked from the config in the current working directory.
+func RunWithClientArgs(e *Env, n int, f func(*Env, *Context, []string) error) cobraRun {
+	return func(cmd *cobra.Command, args []string) {
+		app := DefaultKey
+		if len(args) < n || len(args) > n+1 {
+			fmt.Fprintf(
+				e.Err,
+				"unexpected arguments, expected %d arguments and an optional app name:%+v\n\n",
+				n,
+				args,
+			)
+			cmd.Help()
+			e.Exit(1)
+		}
+		if len(args) == n+1 {
+			app = args[n]
+			args = args[:n]
+		}
+		cl, err := newContext(e, app)
+		if err != nil {
+			fmt.Fprintln(e.Err, ErrorString(e, err))
+			e.Exit(1)
+		}
+		if err := f(e, cl, args); err != nil {
+			fmt.Fprintln(e.Err, ErrorString(e, err))
+			e.Exit(1)
+		}
+	}
+}
@@ -139,3 +139,38 @@ func TestRunWithAppError(t *testing.T) {
 	}()
 	ensure.StringContains(t, h.Err.String(), message)
 }
+
+func TestRunWithClientArgsWrongArgs(t *testing.T) {
+	t.Parallel()
+	h := NewHarness(t)
+	defer h.Stop()
+	h.Env.Exit = func(i int) { panic(exitCode(i)) }
+	func() {
+		defer ensure.PanicDeepEqual(t, exitCode(1))
+		r := RunWithClientArgs(h.Env, 2, nil)
+		r(noOpCmd(), []string{"foo"})
+	}()
+	ensure.StringContains(
+		t,
+		h.Err.String(),
+		"expected 2 arguments and an optional app name",
+	)
+}
+
+func TestRunWithClientArgsNamed(t *testing.T) {
+	t.Parallel()
+	h := NewHarness(t)
+	defer h.Stop()
+	c := &ParseConfig{
+		Applications: map[string]*ParseAppConfig{
+			"a": {ApplicationID: "id", MasterKey: "token"},
+		},
+	}
+	h.MakeWithConfig(jsonStr(t, c))
+	r := RunWithClientArgs(h.Env, 2, func(e *Env, c *Context, args []string) error {
+		ensure.DeepEqual(t, c.AppName, "a")
+		ensure.DeepEqual(t, args, []string{"foo", "bar"})
+		return nil
+	})
+	r(noOpCmd(), []string{"foo", "bar", "a"})
+}
@@ -0,0 +1,388 @@
+package parsecmd
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ParsePlatform/parse-cli/parsecli"
+	"github.com/facebookgo/errgroup"
+	"github.com/facebookgo/stackerr"
+)
+
+// releaseManifestFile is the name of the manifest stored in release archives.
+const releaseManifestFile = "manifest.json"
+
+// releaseManifest describes the release stored in an archive.
+type releaseManifest struct {
+	ReleaseName  string         `json:"releaseName,omitempty"`
+	ParseVersion string         `json:"parseVersion,omitempty"`
+	Checksums    deployFileData `json:"checksums"`
+}
+
+// archiveWriter writes files into an archive.
+type archiveWriter interface {
+	WriteFile(name string, content []byte, modTime time.Time) error
+	Close() error
+}
+
+type tarGzWriter struct {
+	gz *gzip.Writer
+	tw *tar.Writer
+}
+
+func (t *tarGzWriter) WriteFile(name string, content []byte, modTime time.Time) error {
+	err := t.tw.WriteHeader(&tar.Header{
+		Name:     name,
+		Mode:     0644,
+		Size:     int64(len(content)),
+		ModTime:  modTime,
+		Typeflag: tar.TypeReg,
+	})
+	if err != nil {
+		return stackerr.Wrap(err)
+	}
+	_, err = t.tw.Write(content)
+	return stackerr.Wrap(err)
+}
+
+func (t *tarGzWriter) Close() error {
+	if err := t.tw.Close(); err != nil {
+		return stackerr.Wrap(err)
+	}
+	return stackerr.Wrap(t.gz.Close())
+}
+
+type zipWriter struct {
+	zw *zip.Writer
+}
+
+func (z *zipWriter) WriteFile(name string, content []byte, modTime time.Time) error {
+	header := &zip.FileHeader{Name: name, Method: zip.Deflate}
+	header.SetModTime(modTime)
+	header.SetMode(0644)
+	w, err := z.zw.CreateHeader(header)
+	if err != nil {
+		return stackerr.Wrap(err)
+	}
+	_, err = w.Write(content)
+	return stackerr.Wrap(err)
+}
+
+func (z *zipWriter) Close() error {
+	return stackerr.Wrap(z.zw.Close())
+}
+
+// newArchiveWriter picks the archive format from the extension of name.
+func newArchiveWriter(name string, w io.Writer) (archiveWriter, error) {
+	switch {
+	case strings.HasSuffix(name, ".tar.gz"), strings.HasSuffix(name, ".tgz"):
+		gz := gzip.NewWriter(w)
+		return &tarGzWriter{gz: gz, tw: tar.NewWriter(gz)}, nil
+	case strings.HasSuffix(name, ".zip"):
+		return &zipWriter{zw: zip.NewWriter(w)}, nil
+	}
+	return nil, stackerr.Newf(
+		"Unsupported archive %q, expected a .tar.gz, .tgz or .zip file name.",
+		name,
+	)
+}
+
+// archive streams the files of the release into an archive at the given path.
+// No other file is written to disk.
+func (d *downloadCmd) archive(e *parsecli.Env, name string, release *deployInfo) error {
+	file, err := os.Create(name)
+	if err != nil {
+		return stackerr.Wrap(err)
+	}
+	if err := d.writeArchive(e, name, file, release); err != nil {
+		file.Close()
+		os.Remove(name)
+		return err
+	}
+	if err := file.Close(); err != nil {
+		os.Remove(name)
+		return stackerr.Wrap(err)
+	}
+
+	fmt.Fprintf(e.Out, "Successfully archived release %s to %q.\n", release.ReleaseName, name)
+	return nil
+}
+
+func (d *downloadCmd) writeArchive(
+	e *parsecli.Env,
+	name string,
+	w io.Writer,
+	release *deployInfo,
+) error {
+	aw, err := newArchiveWriter(name, w)
+	if err != nil {
+		return err
+	}
+
+	archived := &deployInfo{
+		Checksums: deployFileData{
+			Cloud:  make(map[string]string),
+			Public: make(map[string]string),
+		},
+	}
+	modTime := e.Clock.Now()
+
+	var (
+		wg     errgroup.Group
+		mutex  sync.Mutex
+		failed []string
+	)
+	maxParallel := make(chan struct{}, maxOpenFD)
+	wg.Add(len(release.Versions.Cloud) + len(release.Versions.Public))
+
+	archiveFile := func(target deployTarget, file, version, checksum string) {
+		defer func() {
+			wg.Done()
+			<-maxParallel
+		}()
+
+		content, ok, err := fetchVerified(e, target.fetch, file, version, checksum)
+		if err != nil {
+			wg.Error(err)
+			return
+		}
+
+		mutex.Lock()
+		defer mutex.Unlock()
+		name := path.Join(target.Name, file)
+		if !ok {
+			failed = append(failed, name)
+			return
+		}
+		if err := aw.WriteFile(name, content, modTime); err != nil {
+			wg.Error(err)
+			return
+		}
+		target.checksums(archived)[file] = contentChecksum(content)
+	}
+
+	for _, target := range deployTargets {
+		checksums := target.checksums(release)
+		for file, version := range target.versions(release) {
+			maxParallel <- struct{}{}
+			go archiveFile(target, file, version, checksums[file])
+		}
+	}
+
+	if err := wg.Wait(); err != nil {
+		return err
+	}
+	if err := reportChecksumFailures(e, failed); err != nil {
+		return err
+	}
+
+	manifest := releaseManifest{
+		ReleaseName:  release.ReleaseName,
+		ParseVersion: release.ParseVersion,
+		Checksums:    archived.Checksums,
+	}
+	content, err := json.MarshalIndent(&manifest, "", "  ")
+	if err != nil {
+		return stackerr.Wrap(err)
+	}
+	if err := aw.WriteFile(releaseManifestFile, content, modTime); err != nil {
+		return err
+	}
+	return aw.Close()
+}
+
+// checksums returns the checksums in the manifest for the given source
+// directory.
+func (m *releaseManifest) checksums(dirName string) map[string]string {
+	if dirName == parsecli.CloudDir {
+		return m.Checksums.Cloud
+	}
+	return m.Checksums.Public
+}
+
+// releaseSource holds the files of a release archive extracted to disk.
+type releaseSource struct {
+	Dir      string
+	Manifest *releaseManifest
+}
+
+// openReleaseArchive extracts a release archive written by
+// "parse download --archive" and verifies its files against its manifest.
+func openReleaseArchive(name string) (*releaseSource, error) {
+	dir, err := ioutil.TempDir("", "parse_release_")
+	if err != nil {
+		return nil, stackerr.Wrap(err)
+	}
+	s := &releaseSource{Dir: dir}
+	if err := s.extract(name); err != nil {
+		s.remove()
+		return nil, err
+	}
+	if err := s.verify(); err != nil {
+		s.remove()
+		return nil, err
+	}
+	return s, nil
+}
+
+func (s *releaseSource) extract(name string) error {
+	switch {
+	case strings.HasSuffix(name, ".tar.gz"), strings.HasSuffix(name, ".tgz"):
+		file, err := os.Open(name)
+		if err != nil {
+			return stackerr.Wrap(err)
+		}
+		defer file.Close()
+		gz, err := gzip.NewReader(file)
+		if err != nil {
+			return stackerr.Wrap(err)
+		}
+		tr := tar.NewReader(gz)
+		for {
+			header, err := tr.Next()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				return stackerr.Wrap(err)
+			}
+			if !header.FileInfo().Mode().IsRegular() {
+				continue
+			}
+			if err := s.writeFile(header.Name, tr); err != nil {
+				return err
+			}
+		}
+	case strings.HasSuffix(name, ".zip"):
+		zr, err := zip.OpenReader(name)
+		if err != nil {
+			return stackerr.Wrap(err)
+		}
+		defer zr.Close()
+		for _, file := range zr.File {
+			if !file.FileInfo().Mode().IsRegular() {
+				continue
+			}
+			r, err := file.Open()
+			if err != nil {
+				return stackerr.Wrap(err)
+			}
+			err = s.writeFile(file.Name, r)
+			r.Close()
+			if err != nil {
+				return err
+			}
+		}
+	default:
+		return stackerr.Newf(
+			"Unsupported archive %q, expected a .tar.gz, .tgz or .zip file name.",
+			name,
+		)
+	}
+
+	content, err := ioutil.ReadFile(filepath.Join(s.Dir, releaseManifestFile))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return stackerr.Newf("Archive %q has no %s.", name, releaseManifestFile)
+		}
+		return stackerr.Wrap(err)
+	}
+	s.Manifest = &releaseManifest{}
+	if err := json.Unmarshal(content, s.Manifest); err != nil {
+		return stackerr.Wrap(err)
+	}
+	if s.Manifest.Checksums.Cloud == nil {
+		s.Manifest.Checksums.Cloud = make(map[string]string)
+	}
+	if s.Manifest.Checksums.Public == nil {
+		s.Manifest.Checksums.Public = make(map[string]string)
+	}
+	return nil
+}
+
+func (s *releaseSource) writeFile(name string, r io.Reader) error {
+	clean := path.Clean(name)
+	if path.IsAbs(clean) || clean == ".." || strings.HasPrefix(clean, "../") {
+		return stackerr.Newf("Invalid file name %q in archive.", name)
+	}
+	dst := filepath.Join(s.Dir, filepath.FromSlash(clean))
+	if err := os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+		return stackerr.Wrap(err)
+	}
+	file, err := os.Create(dst)
+	if err != nil {
+		return stackerr.Wrap(err)
+	}
+	if _, err := io.Copy(file, r); err != nil {
+		file.Close()
+		return stackerr.Wrap(err)
+	}
+	return stackerr.Wrap(file.Close())
+}
+
+// verify ensures the archive holds exactly the files listed in the manifest,
+// with the listed content.
+func (s *releaseSource) verify() error {
+	var files []string
+	err := filepath.Walk(s.Dir, func(name string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if !info.IsDir() {
+			files = append(files, name)
+		}
+		return nil
+	})
+	if err != nil {
+		return stackerr.Wrap(err)
+	}
+	actual, err := (&deployCmd{}).computeChecksums(files, relativeNamer(s.Dir))
+	if err != nil {
+		return err
+	}
+	delete(actual, releaseManifestFile)
+
+	expected := make(map[string]string)
+	for _, target := range deployTargets {
+		for file, checksum := range s.Manifest.checksums(target.Name) {
+			expected[path.Join(target.Name, file)] = checksum
+		}
+	}
+
+	var problems []string
+	for file, checksum := range actual {
+		expectedChecksum, ok := expected[file]
+		switch {
+		case !ok:
+			problems = append(problems, fmt.Sprintf("%s is not listed in the manifest", file))
+		case checksum != expectedChecksum:
+			problems = append(problems, fmt.Sprintf("%s does not match its checksum", file))
+		}
+	}
+	for file := range expected {
+		if _, ok := actual[file]; !ok {
+			problems = append(problems, fmt.Sprintf("%s is missing from the archive", file))
+		}
+	}
+	if len(problems) != 0 {
+		sort.Strings(problems)
+		return stackerr.Newf("Archive does not match its manifest:\n%s", strings.Join(problems, "\n"))
+	}
+	return nil
+}
+
+func (s *releaseSource) remove() error {
+	return stackerr.Wrap(os.RemoveAll(s.Dir))
+}
@@ -0,0 +1,213 @@
+package parsecmd
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ParsePlatform/parse-cli/parsecli"
+	"github.com/facebookgo/ensure"
+)
+
+func newArchiveHarness(t testing.TB, name string) (*downloadCmd, func()) {
+	tempDir, err := ioutil.TempDir("", "archive_")
+	ensure.Nil(t, err)
+	h, d := newDownloadHarness(t)
+	d.release.ReleaseName = "v1"
+	d.release.ParseVersion = "1.6.0"
+	d.archiveName = filepath.Join(tempDir, name)
+	ensure.Nil(t, d.run(h.Env, &parsecli.Context{Config: defaultParseConfig}))
+	return d, func() {
+		h.Stop()
+		os.RemoveAll(tempDir)
+	}
+}
+
+func ensureArchivedRelease(t testing.TB, files map[string][]byte) {
+	ensure.DeepEqual(t, len(files), 3)
+	ensure.DeepEqual(t, string(files["cloud/main.js"]), "content")
+	ensure.DeepEqual(t, files["public/index.html"], []byte{1, 1, 2, 3, 5, 8, 13})
+
+	var manifest releaseManifest
+	ensure.Nil(t, json.Unmarshal(files[releaseManifestFile], &manifest))
+	ensure.DeepEqual(t, manifest, releaseManifest{
+		ReleaseName:  "v1",
+		ParseVersion: "1.6.0",
+		Checksums: deployFileData{
+			Cloud:  map[string]string{"main.js": "9a0364b9e99bb480dd25e1f0284c8555"},
+			Public: map[string]string{"index.html": "ea46dea1ca5f0b7a728aa3c2a87ae8a1"},
+		},
+	})
+}
+
+func TestArchiveTarGz(t *testing.T) {
+	t.Parallel()
+	d, done := newArchiveHarness(t, "release.tar.gz")
+	defer done()
+
+	f, err := os.Open(d.archiveName)
+	ensure.Nil(t, err)
+	defer f.Close()
+	gz, err := gzip.NewReader(f)
+	ensure.Nil(t, err)
+	tr := tar.NewReader(gz)
+
+	files := make(map[string][]byte)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		ensure.Nil(t, err)
+		content, err := ioutil.ReadAll(tr)
+		ensure.Nil(t, err)
+		files[header.Name] = content
+	}
+	ensureArchivedRelease(t, files)
+}
+
+func TestArchiveZip(t *testing.T) {
+	t.Parallel()
+	d, done := newArchiveHarness(t, "release.zip")
+	defer done()
+
+	zr, err := zip.OpenReader(d.archiveName)
+	ensure.Nil(t, err)
+	defer zr.Close()
+
+	files := make(map[string][]byte)
+	for _, file := range zr.File {
+		r, err := file.Open()
+		ensure.Nil(t, err)
+		content, err := ioutil.ReadAll(r)
+		ensure.Nil(t, err)
+		r.Close()
+		files[file.Name] = content
+	}
+	ensureArchivedRelease(t, files)
+}
+
+func TestArchiveUnsupported(t *testing.T) {
+	t.Parallel()
+	tempDir, err := ioutil.TempDir("", "archive_")
+	ensure.Nil(t, err)
+	defer os.RemoveAll(tempDir)
+	h, d := newDownloadHarness(t)
+	defer h.Stop()
+
+	d.archiveName = filepath.Join(tempDir, "release.rar")
+	ensure.Err(t, d.run(h.Env, &parsecli.Context{Config: defaultParseConfig}), regexp.MustCompile("Unsupported archive"))
+	_, err = os.Stat(d.archiveName)
+	ensure.True(t, os.IsNotExist(err))
+}
+
+func writeTestArchive(t testing.TB, name string, files map[string]string, manifest *releaseManifest) {
+	f, err := os.Create(name)
+	ensure.Nil(t, err)
+	defer f.Close()
+	aw, err := newArchiveWriter(name, f)
+	ensure.Nil(t, err)
+	for file, content := range files {
+		ensure.Nil(t, aw.WriteFile(file, []byte(content), time.Now()))
+	}
+	content, err := json.Marshal(manifest)
+	ensure.Nil(t, err)
+	ensure.Nil(t, aw.WriteFile(releaseManifestFile, content, time.Now()))
+	ensure.Nil(t, aw.Close())
+}
+
+func TestDeployFromArchive(t *testing.T) {
+	t.Parallel()
+	h := setupForDeploy(t, &deployInfo{})
+	defer h.Stop()
+
+	var (
+		mutex    sync.Mutex
+		uploaded = make(map[string]string)
+	)
+	ht := h.Env.ParseAPIClient.APIClient.Transport
+	h.Env.ParseAPIClient.APIClient.Transport = parsecli.TransportFunc(
+		func(r *http.Request) (*http.Response, error) {
+			if r.URL.Path == "/1/scripts" || r.URL.Path == "/1/hosted_files" {
+				var file struct {
+					Name    string `json:"name"`
+					Content []byte `json:"content"`
+				}
+				ensure.Nil(t, json.NewDecoder(r.Body).Decode(&file))
+				mutex.Lock()
+				uploaded[path.Join(r.URL.Path, file.Name)] = string(file.Content)
+				mutex.Unlock()
+			}
+			return ht.RoundTrip(r)
+		})
+
+	tempDir, err := ioutil.TempDir("", "archive_")
+	ensure.Nil(t, err)
+	defer os.RemoveAll(tempDir)
+
+	manifest := &releaseManifest{
+		ReleaseName:  "v1",
+		ParseVersion: "1.6.0",
+		Checksums: deployFileData{
+			Cloud:  map[string]string{"main.js": "9a0364b9e99bb480dd25e1f0284c8555"},
+			Public: map[string]string{"index.html": "9a0364b9e99bb480dd25e1f0284c8555"},
+		},
+	}
+	name := filepath.Join(tempDir, "release.tar.gz")
+	writeTestArchive(t, name, map[string]string{
+		"cloud/main.js":     "content",
+		"public/index.html": "content",
+	}, manifest)
+
+	d := deployCmd{From: name}
+	d.source, err = openReleaseArchive(name)
+	ensure.Nil(t, err)
+	defer d.source.remove()
+
+	res, err := d.deploy(d.source.Manifest.ParseVersion, nil, false, h.Env)
+	ensure.Nil(t, err)
+	ensure.DeepEqual(t, res.Checksums, manifest.Checksums)
+	ensure.DeepEqual(t, uploaded, map[string]string{
+		"/1/scripts/main.js":         "content",
+		"/1/hosted_files/index.html": "content",
+	})
+}
+
+func TestOpenReleaseArchiveMismatch(t *testing.T) {
+	t.Parallel()
+	tempDir, err := ioutil.TempDir("", "archive_")
+	ensure.Nil(t, err)
+	defer os.RemoveAll(tempDir)
+
+	name := filepath.Join(tempDir, "release.zip")
+	writeTestArchive(t, name, map[string]string{
+		"cloud/main.js":     "changed",
+		"cloud/extra.js":    "content",
+		"public/index.html": "content",
+	}, &releaseManifest{
+		Checksums: deployFileData{
+			Cloud: map[string]string{
+				"main.js":  "9a0364b9e99bb480dd25e1f0284c8555",
+				"other.js": "9a0364b9e99bb480dd25e1f0284c8555",
+			},
+			Public: map[string]string{"index.html": "9a0364b9e99bb480dd25e1f0284c8555"},
+		},
+	})
+
+	_, err = openReleaseArchive(name)
+	ensure.Err(t, err, regexp.MustCompile(`Archive does not match its manifest:
+cloud/extra.js is not listed in the manifest
+cloud/main.js does not match its checksum
+cloud/other.js is missing from the archive`))
+}
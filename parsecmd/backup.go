@@ -0,0 +1,240 @@
+package parsecmd
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/ParsePlatform/parse-cli/parsecli"
+	"github.com/facebookgo/stackerr"
+)
+
+const (
+	backupsDir         = "backups"
+	backupManifestFile = "manifest.json"
+	// backupTimeFormat is the format of the time in backup directory names.
+	backupTimeFormat = "20060102T150405"
+)
+
+var errNoBackup = errors.New("No backup found to restore.")
+
+// projectBackup saves the project files overwritten by a forced download so
+// they can be restored if the download fails or has to be undone.
+type projectBackup struct {
+	// Saved lists the files copied into the backup.
+	Saved []string `json:"saved,omitempty"`
+	// Created lists the files that did not exist before the download.
+	Created []string `json:"created,omitempty"`
+
+	dir   string
+	mutex sync.Mutex
+}
+
+func backupsRoot(e *parsecli.Env) string {
+	return filepath.Join(e.Root, parsecli.StateDir, backupsDir)
+}
+
+// backupDirs returns the backup directories of the project, oldest first.
+// Backups are named after a sequence number followed by the time they were
+// taken, directories with other names are considered older than all of them.
+func backupDirs(e *parsecli.Env) ([]string, error) {
+	infos, err := ioutil.ReadDir(backupsRoot(e))
+	if err != nil {
+		return nil, err
+	}
+	var names []string
+	for _, info := range infos {
+		if info.IsDir() {
+			names = append(names, info.Name())
+		}
+	}
+	sort.Sort(byBackupSequence(names))
+	return names, nil
+}
+
+type byBackupSequence []string
+
+func (b byBackupSequence) Len() int      { return len(b) }
+func (b byBackupSequence) Swap(i, j int) { b[i], b[j] = b[j], b[i] }
+func (b byBackupSequence) Less(i, j int) bool {
+	si, sj := backupSequence(b[i]), backupSequence(b[j])
+	if si != sj {
+		return si < sj
+	}
+	return b[i] < b[j]
+}
+
+// backupSequence returns the sequence number of a backup directory, or -1.
+func backupSequence(name string) int {
+	if i := strings.Index(name, "-"); i >= 0 {
+		name = name[:i]
+	}
+	n, err := strconv.Atoi(name)
+	if err != nil || n < 0 {
+		return -1
+	}
+	return n
+}
+
+func newProjectBackup(e *parsecli.Env) (*projectBackup, error) {
+	root := backupsRoot(e)
+	if err := os.MkdirAll(root, 0755); err != nil {
+		return nil, stackerr.Wrap(err)
+	}
+	names, err := backupDirs(e)
+	if err != nil {
+		return nil, stackerr.Wrap(err)
+	}
+	next := 1
+	if len(names) != 0 {
+		if n := backupSequence(names[len(names)-1]); n >= 0 {
+			next = n + 1
+		}
+	}
+	taken := e.Clock.Now().UTC().Format(backupTimeFormat)
+	for {
+		dir := filepath.Join(root, fmt.Sprintf("%06d-%s", next, taken))
+		err := os.Mkdir(dir, 0755)
+		if err == nil {
+			return &projectBackup{dir: dir}, nil
+		}
+		if !os.IsExist(err) {
+			return nil, stackerr.Wrap(err)
+		}
+		// taken by a concurrent download
+		next++
+	}
+}
+
+// latestProjectBackup loads the most recent backup of the project.
+func latestProjectBackup(e *parsecli.Env) (*projectBackup, error) {
+	names, err := backupDirs(e)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, errNoBackup
+		}
+		return nil, stackerr.Wrap(err)
+	}
+	for i := len(names) - 1; i >= 0; i-- {
+		b := &projectBackup{dir: filepath.Join(backupsRoot(e), names[i])}
+		content, err := ioutil.ReadFile(filepath.Join(b.dir, backupManifestFile))
+		if err != nil {
+			// backup was interrupted before it was complete
+			continue
+		}
+		if err := json.Unmarshal(content, b); err != nil {
+			return nil, stackerr.Wrap(err)
+		}
+		return b, nil
+	}
+	return nil, errNoBackup
+}
+
+func copyFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return stackerr.Wrap(err)
+	}
+	defer in.Close()
+
+	info, err := in.Stat()
+	if err != nil {
+		return stackerr.Wrap(err)
+	}
+	if err := os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+		return stackerr.Wrap(err)
+	}
+	out, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, info.Mode())
+	if err != nil {
+		return stackerr.Wrap(err)
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return stackerr.Wrap(err)
+	}
+	return stackerr.Wrap(out.Close())
+}
+
+// save backs up the file with the given name, relative to root, if it exists.
+func (b *projectBackup) save(root, name string) error {
+	src := filepath.Join(root, name)
+	_, err := os.Stat(src)
+	if os.IsNotExist(err) {
+		b.mutex.Lock()
+		defer b.mutex.Unlock()
+		b.Created = append(b.Created, filepath.ToSlash(name))
+		return nil
+	}
+	if err != nil {
+		return stackerr.Wrap(err)
+	}
+	if err := copyFile(src, filepath.Join(b.dir, name)); err != nil {
+		return err
+	}
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	b.Saved = append(b.Saved, filepath.ToSlash(name))
+	return nil
+}
+
+// commit records the backup as complete.
+func (b *projectBackup) commit() error {
+	sort.Strings(b.Saved)
+	sort.Strings(b.Created)
+	content, err := json.MarshalIndent(b, "", "  ")
+	if err != nil {
+		return stackerr.Wrap(err)
+	}
+	return stackerr.Wrap(
+		ioutil.WriteFile(filepath.Join(b.dir, backupManifestFile), content, 0600),
+	)
+}
+
+// removeOlder deletes the backups taken before this one, once the download it
+// backs up succeeded, as only the latest backup can be restored.
+func (b *projectBackup) removeOlder(e *parsecli.Env) error {
+	names, err := backupDirs(e)
+	if err != nil {
+		return stackerr.Wrap(err)
+	}
+	for _, name := range names {
+		if name == filepath.Base(b.dir) {
+			break
+		}
+		if err := os.RemoveAll(filepath.Join(backupsRoot(e), name)); err != nil {
+			return stackerr.Wrap(err)
+		}
+	}
+	return nil
+}
+
+// restore puts back the saved files and removes the files created since the
+// backup was taken.
+func (b *projectBackup) restore(root string) error {
+	for _, name := range b.Saved {
+		name = filepath.FromSlash(name)
+		if err := copyFile(filepath.Join(b.dir, name), filepath.Join(root, name)); err != nil {
+			return err
+		}
+	}
+	for _, name := range b.Created {
+		err := os.Remove(filepath.Join(root, filepath.FromSlash(name)))
+		if err != nil && !os.IsNotExist(err) {
+			return stackerr.Wrap(err)
+		}
+	}
+	return nil
+}
+
+// remove deletes the backup.
+func (b *projectBackup) remove() error {
+	return stackerr.Wrap(os.RemoveAll(b.dir))
+}
@@ -0,0 +1,191 @@
+package parsecmd
+
+import (
+	"crypto/md5"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/ParsePlatform/parse-cli/parsecli"
+	"github.com/facebookgo/stackerr"
+)
+
+const (
+	checksumCacheFile = "checksums.json"
+	// checksumAlgorithm names the checksums computed by computeChecksums,
+	// cached checksums computed differently are discarded.
+	checksumAlgorithm = "md5"
+)
+
+// cachedChecksum is the checksum of a file, valid as long as the file keeps
+// the same size, modification time and inode.
+type cachedChecksum struct {
+	Size     int64  `json:"size"`
+	ModTime  int64  `json:"mtime"`
+	Inode    uint64 `json:"inode"`
+	Checksum string `json:"checksum"`
+}
+
+type checksumCacheData struct {
+	Algorithm string `json:"algorithm"`
+	// Ignore is the checksum of the .parseignore file the cache was built
+	// with.
+	Ignore string                    `json:"ignore"`
+	Files  map[string]cachedChecksum `json:"files"`
+}
+
+// checksumCache persists the checksums of the project files, so that files
+// which did not change are not hashed again. A nil checksumCache caches
+// nothing.
+type checksumCache struct {
+	path string
+	root string
+	// written is when the cache was last written. Files modified at the same
+	// time may have changed after they were hashed, so their checksums are
+	// not trusted.
+	written int64
+
+	mutex sync.Mutex
+	data  checksumCacheData
+	used  map[string]cachedChecksum
+	dirty bool
+}
+
+// loadChecksumCache loads the checksum cache of the project. The cache is
+// empty if it does not exist, cannot be read, was computed with another
+// algorithm or with other .parseignore rules.
+func loadChecksumCache(e *parsecli.Env) *checksumCache {
+	c := &checksumCache{
+		path: filepath.Join(e.Root, parsecli.StateDir, checksumCacheFile),
+		root: e.Root,
+		used: make(map[string]cachedChecksum),
+	}
+	ignore, err := ioutil.ReadFile(filepath.Join(e.Root, parseIgnore))
+	if err != nil && !os.IsNotExist(err) {
+		// the rules are unknown, so the cache cannot be trusted nor saved
+		return nil
+	}
+	c.data = checksumCacheData{
+		Algorithm: checksumAlgorithm,
+		Ignore:    fmt.Sprintf("%x", md5.Sum(ignore)),
+		Files:     make(map[string]cachedChecksum),
+	}
+
+	info, err := os.Stat(c.path)
+	if err != nil {
+		return c
+	}
+	content, err := ioutil.ReadFile(c.path)
+	if err != nil {
+		return c
+	}
+	var data checksumCacheData
+	if err := json.Unmarshal(content, &data); err != nil {
+		return c
+	}
+	if data.Algorithm != c.data.Algorithm || data.Ignore != c.data.Ignore {
+		// the cache is rewritten with the new rules
+		c.dirty = true
+		return c
+	}
+	if data.Files != nil {
+		c.data.Files = data.Files
+	}
+	c.written = info.ModTime().UnixNano()
+	return c
+}
+
+func (c *checksumCache) key(name string) string {
+	if rel, err := filepath.Rel(c.root, name); err == nil {
+		return filepath.ToSlash(rel)
+	}
+	return filepath.ToSlash(name)
+}
+
+// lookup returns the cached checksum of a file, if it did not change since it
+// was cached.
+func (c *checksumCache) lookup(name string, info os.FileInfo) (string, bool) {
+	if c == nil {
+		return "", false
+	}
+	key := c.key(name)
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	cached, ok := c.data.Files[key]
+	if !ok || cached.Size != info.Size() || cached.ModTime != info.ModTime().UnixNano() ||
+		cached.Inode != fileInode(info) || cached.ModTime >= c.written {
+		return "", false
+	}
+	c.used[key] = cached
+	return cached.Checksum, true
+}
+
+// store caches the checksum of a file.
+func (c *checksumCache) store(name string, info os.FileInfo, checksum string) {
+	if c == nil {
+		return
+	}
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.used[c.key(name)] = cachedChecksum{
+		Size:     info.Size(),
+		ModTime:  info.ModTime().UnixNano(),
+		Inode:    fileInode(info),
+		Checksum: checksum,
+	}
+	c.dirty = true
+}
+
+// save writes the checksums of the files looked up since the cache was
+// loaded, so that removed files are forgotten. Failing to save the cache only
+// makes the next deploy hash the files again, so it is only reported.
+func (c *checksumCache) save(e *parsecli.Env) {
+	if c == nil {
+		return
+	}
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if !c.dirty && len(c.used) == len(c.data.Files) {
+		return
+	}
+	c.data.Files = c.used
+	if err := c.write(); err != nil {
+		fmt.Fprintf(
+			e.Err,
+			"Could not save the file checksums to %s:\n%s\n",
+			c.path,
+			parsecli.ErrorString(e, err),
+		)
+	}
+}
+
+// write replaces the cache file, so that an interrupted write does not leave a
+// partial cache.
+func (c *checksumCache) write() error {
+	content, err := json.Marshal(&c.data)
+	if err != nil {
+		return stackerr.Wrap(err)
+	}
+	if err := os.MkdirAll(filepath.Dir(c.path), 0755); err != nil {
+		return stackerr.Wrap(err)
+	}
+	file, err := ioutil.TempFile(filepath.Dir(c.path), checksumCacheFile)
+	if err != nil {
+		return stackerr.Wrap(err)
+	}
+	_, err = file.Write(content)
+	if cerr := file.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Rename(file.Name(), c.path)
+	}
+	if err != nil {
+		os.Remove(file.Name())
+		return stackerr.Wrap(err)
+	}
+	return nil
+}
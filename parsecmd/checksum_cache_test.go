@@ -0,0 +1,82 @@
+package parsecmd
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/ParsePlatform/parse-cli/parsecli"
+	"github.com/facebookgo/ensure"
+)
+
+// cachedChecksumOf computes the checksum of a file through the cache of the
+// project, and saves the cache.
+func cachedChecksumOf(t testing.TB, h *parsecli.Harness, file string) string {
+	d := deployCmd{cache: loadChecksumCache(h.Env)}
+	res, err := d.computeChecksums([]string{file}, relativeNamer(h.Env.Root))
+	ensure.Nil(t, err)
+	d.cache.save(h.Env)
+	return res[filepath.ToSlash(filepath.Join(parsecli.CloudDir, "main.js"))]
+}
+
+// rewrite changes the content of file without changing its size nor its
+// modification time, so that only an uncached checksum sees the change.
+func rewrite(t testing.TB, file, content string, modTime time.Time) {
+	ensure.Nil(t, ioutil.WriteFile(file, []byte(content), 0600))
+	ensure.Nil(t, os.Chtimes(file, modTime, modTime))
+}
+
+func TestChecksumCache(t *testing.T) {
+	t.Parallel()
+	h := createParseProject(t)
+	defer h.Stop()
+
+	file := filepath.Join(h.Env.Root, parsecli.CloudDir, "main.js")
+	modTime := time.Now().Add(-time.Hour)
+	rewrite(t, file, "aaaa", modTime)
+	const aaaa = "74b87337454200d4d33f80c4663dc5e5"
+	ensure.DeepEqual(t, cachedChecksumOf(t, h, file), aaaa)
+
+	rewrite(t, file, "bbbb", modTime)
+	ensure.DeepEqual(t, cachedChecksumOf(t, h, file), aaaa)
+
+	// a modified file is hashed again
+	rewrite(t, file, "bbbb", modTime.Add(time.Second))
+	const bbbb = "65ba841e01d6db7733e90a5b7f9e6f80"
+	ensure.DeepEqual(t, cachedChecksumOf(t, h, file), bbbb)
+
+	// as are all files when the ignore rules change
+	rewrite(t, file, "cccc", modTime.Add(time.Second))
+	ensure.Nil(t, ioutil.WriteFile(filepath.Join(h.Env.Root, parseIgnore), []byte("*.txt"), 0600))
+	const cccc = "41fcba09f2bdcdf315ba4119dc7978dd"
+	ensure.DeepEqual(t, cachedChecksumOf(t, h, file), cccc)
+
+	// or when the checksums were computed by another algorithm
+	rewrite(t, file, "dddd", modTime.Add(time.Second))
+	cache := loadChecksumCache(h.Env)
+	cache.data.Algorithm = "sha1"
+	cache.used = cache.data.Files
+	ensure.Nil(t, cache.write())
+	const dddd = "11ddbaf3386aea1f2974eee984542152"
+	ensure.DeepEqual(t, cachedChecksumOf(t, h, file), dddd)
+}
+
+func TestChecksumCacheModifiedWhenWritten(t *testing.T) {
+	t.Parallel()
+	h := createParseProject(t)
+	defer h.Stop()
+
+	file := filepath.Join(h.Env.Root, parsecli.CloudDir, "main.js")
+	modTime := time.Now().Add(-time.Hour)
+	rewrite(t, file, "aaaa", modTime)
+	cachedChecksumOf(t, h, file)
+
+	// a file modified when the cache was written may have changed after it
+	// was hashed
+	cacheFile := filepath.Join(h.Env.Root, parsecli.StateDir, checksumCacheFile)
+	ensure.Nil(t, os.Chtimes(cacheFile, modTime, modTime))
+	rewrite(t, file, "bbbb", modTime)
+	ensure.DeepEqual(t, cachedChecksumOf(t, h, file), "65ba841e01d6db7733e90a5b7f9e6f80")
+}
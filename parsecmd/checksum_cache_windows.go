@@ -0,0 +1,9 @@
+package parsecmd
+
+import "os"
+
+// fileInode returns 0, as inodes are not available from os.FileInfo on
+// Windows.
+func fileInode(info os.FileInfo) uint64 {
+	return 0
+}
@@ -2,6 +2,7 @@ package parsecmd
 
 import (
 	"crypto/md5"
+	"encoding/json"
 	"fmt"
 	"io"
 	"io/ioutil"
@@ -9,6 +10,7 @@ import (
 	"net/http"
 	"net/url"
 	"os"
+	"path"
 	"path/filepath"
 	"reflect"
 	"sort"
@@ -18,7 +20,6 @@ import (
 
 	"github.com/ParsePlatform/parse-cli/parsecli"
 	"github.com/facebookgo/errgroup"
-	"github.com/facebookgo/jsonpipe"
 	"github.com/facebookgo/stackerr"
 	"github.com/facebookgo/symwalk"
 	"github.com/spf13/cobra"
@@ -33,8 +34,68 @@ type deployCmd struct {
 	Description string
 	Force       bool
 	Verbose     bool
+	DryRun      bool
+	JSON        bool
+	Confirmed   bool
+	From        string
 	Retries     int
+	Concurrency int
 	wait        func(int) time.Duration
+	source      *releaseSource
+	sources     sourceDirList
+	hooks       *deployHooks
+	// timings prints how long each phase of the deploy took, it is set by an
+	// explicit --verbose as Verbose is on by default.
+	timings bool
+
+	// uploadWait returns how long to wait before retrying the upload of a
+	// file, uploadBackoff is used when it is nil.
+	uploadWait func(attempt int) time.Duration
+
+	// predeployed is set once the predeploy hook succeeded, so that retries
+	// do not run it again.
+	predeployed bool
+	// hookChecksums and hookErr record the files the predeploy hook last ran
+	// on in develop mode, and how it ended.
+	hookChecksums *deployFileData
+	hookErr       error
+
+	// uploaded records the files uploaded by earlier attempts, so that a
+	// retried deploy does not upload them again.
+	uploaded *deployInfo
+	// state persists uploaded files, so that they are reused by the next
+	// deploy if this one is interrupted.
+	state *uploadState
+
+	// Apps lists the apps to deploy to, separated by commas, instead of a
+	// single app. AllApps deploys to every app in the config.
+	Apps    string
+	AllApps bool
+	// precomputed holds the checksums of the files of each source directory,
+	// computed once when deploying to several apps.
+	precomputed map[string]map[string]string
+	// cache holds the checksums of the project files computed by earlier
+	// deploys, it is nil when checksums are not cached.
+	cache *checksumCache
+	// result is the outcome of the last successful deploy.
+	result *deployResult
+}
+
+// sourceDirs returns the directories deployed by the command.
+func (d *deployCmd) sourceDirs() sourceDirList {
+	if d.sources == nil {
+		return defaultSourceDirs
+	}
+	return d.sources
+}
+
+// concurrency returns how many files are uploaded at the same time, maxOpenFD
+// when it is not set.
+func (d *deployCmd) concurrency() int {
+	if d.Concurrency <= 0 {
+		return maxOpenFD
+	}
+	return d.Concurrency
 }
 
 func (d *deployCmd) getSourceFiles(
@@ -132,17 +193,27 @@ func (d *deployCmd) computeChecksums(files []string,
 			wg.Error(stackerr.Wrap(err))
 			return
 		}
-		h := md5.New()
-		if _, err := io.Copy(h, file); err != nil {
+		info, err := file.Stat()
+		if err != nil {
 			wg.Error(stackerr.Wrap(err))
 			return
 		}
+		checksum, ok := d.cache.lookup(name, info)
+		if !ok {
+			h := md5.New()
+			if _, err := io.Copy(h, file); err != nil {
+				wg.Error(stackerr.Wrap(err))
+				return
+			}
+			checksum = fmt.Sprintf("%x", h.Sum(nil))
+			d.cache.store(name, info, checksum)
+		}
 		if err := file.Close(); err != nil {
 			wg.Error(stackerr.Wrap(err))
 			return
 		}
 		mutex.Lock()
-		checksums[normalizeName(name)] = fmt.Sprintf("%x", h.Sum(nil))
+		checksums[normalizeName(name)] = checksum
 		defer mutex.Unlock()
 	}
 
@@ -160,26 +231,31 @@ func (d *deployCmd) computeChecksums(files []string,
 }
 
 func (d *deployCmd) uploadFile(filename, endpoint string, e *parsecli.Env,
-	normalizeName func(string) string) (string, error) {
-	content, err := ioutil.ReadFile(filename)
+	normalizeName func(string) string, transfer *parsecli.Transfer) (version string, status int, err error) {
+	file, err := os.Open(filename)
 	if err != nil {
-		return "", err
-	}
-
-	req, err := http.NewRequest(
-		"POST",
-		endpoint,
-		ioutil.NopCloser(
-			jsonpipe.Encode(
-				map[string]interface{}{
-					"name":    normalizeName(filename),
-					"content": content,
-				},
-			),
-		),
-	)
+		return "", 0, err
+	}
+	defer file.Close()
+
+	content := transfer.Reader(file)
+	defer func() {
+		if err != nil {
+			// the file is counted again when it is retried
+			content.Undo()
+		}
+	}()
+
+	// closing the body stops the encoder if the request ends before reading
+	// the whole file, and waits for it, so that the content is not read
+	// anymore when it is undone and the file closed by the deferred calls
+	// above
+	body := encodeUpload(normalizeName(filename), content)
+	defer body.Close()
+
+	req, err := http.NewRequest("POST", endpoint, body)
 	if err != nil {
-		return "", stackerr.Wrap(err)
+		return "", 0, stackerr.Wrap(err)
 	}
 
 	mimeType := mime.TypeByExtension(filepath.Ext(filename))
@@ -192,14 +268,28 @@ func (d *deployCmd) uploadFile(filename, endpoint string, e *parsecli.Env,
 		Version string `json:"version"`
 	}
 
-	if _, err := e.ParseAPIClient.Do(req, nil, &res); err != nil {
-		return "", stackerr.Wrap(err)
+	resp, err := e.ParseAPIClient.Do(req, nil, &res)
+	if resp != nil {
+		status = resp.StatusCode
+	}
+	if err != nil {
+		return "", status, stackerr.Wrap(err)
 	}
 
 	if res.Version == "" {
-		return "", stackerr.Newf("Malformed response when trying to upload %s", filename)
+		return "", status, stackerr.Newf("Malformed response when trying to upload %s", filename)
+	}
+	return res.Version, status, nil
+}
+
+// relativeNamer returns a function that converts paths under dir into the
+// slash separated names used by the server.
+func relativeNamer(dir string) func(string) string {
+	namePrefixLen := len(filepath.Join(dir, "1")) - 1
+	return func(name string) string {
+		name = filepath.ToSlash(filepath.Clean(name))
+		return name[namePrefixLen:]
 	}
-	return res.Version, nil
 }
 
 type uploader struct {
@@ -209,82 +299,122 @@ type uploader struct {
 	Env           *parsecli.Env
 	PrevChecksums map[string]string
 	PrevVersions  map[string]string
+
+	// Root and Checksums are set when deploying from a release archive, the
+	// files listed in Checksums are then uploaded from Root instead of walking
+	// the project.
+	Root      string
+	Checksums map[string]string
+
+	// Uploaded is called with each file as soon as it is uploaded.
+	Uploaded func(name, checksum, version string)
+
+	// set by prepareUpload and uploadTrees
+	normalizeName func(string) string
+	changedFiles  []string
+	checksums     map[string]string
+	versions      map[string]string
+	failures      []uploadFailure
+	err           error
 }
 
-func (d *deployCmd) uploadSourceFiles(u *uploader) (map[string]string,
-	map[string]string, error) {
-	sourceFiles, ignoredFiles, err := d.getSourceFiles(filepath.Join(u.Env.Root, u.DirName), u.Suffixes, u.Env)
-	if err != nil {
-		return nil, nil, err
+// message describes the files of the uploader for humans.
+func (u *uploader) message() string {
+	switch u.EndPoint {
+	case scriptsTarget.EndPoint:
+		return scriptsTarget.Message
+	case hostingTarget.EndPoint:
+		return hostingTarget.Message
 	}
+	return u.DirName
+}
 
-	namePrefixLen := len(filepath.Join(u.Env.Root, u.DirName, "1")) - 1
-	normalizeName := func(name string) string {
-		name = filepath.ToSlash(filepath.Clean(name))
-		return name[namePrefixLen:]
-	}
+// deployTimings is how long each phase of a deploy took.
+type deployTimings struct {
+	Walk    time.Duration
+	Hash    time.Duration
+	Upload  time.Duration
+	Release time.Duration
+}
 
-	currentChecksums, err := d.computeChecksums(sourceFiles, normalizeName)
-	if err != nil {
-		return nil, nil, err
+// endPhase adds the time elapsed since start to the phase and returns the
+// current time, to start the next phase.
+func endPhase(e *parsecli.Env, phase *time.Duration, start time.Time) time.Time {
+	now := e.Clock.Now()
+	*phase += now.Sub(start)
+	return now
+}
+
+func (t *deployTimings) String() string {
+	ms := func(d time.Duration) time.Duration { return d / time.Millisecond * time.Millisecond }
+	s := fmt.Sprintf("walk %s, hash %s, upload %s", ms(t.Walk), ms(t.Hash), ms(t.Upload))
+	if t.Release != 0 {
+		s += fmt.Sprintf(", release %s", ms(t.Release))
 	}
+	return s
+}
 
-	var mutex sync.Mutex
-	maxParallel := make(chan struct{}, maxOpenFD)
-	var wg errgroup.Group
-	currentVersions := make(map[string]string)
+// prepareUpload finds the files of the uploader, computes their checksums and
+// lists the files which changed since the previous deploy.
+func (d *deployCmd) prepareUpload(u *uploader, timings *deployTimings) error {
+	root := u.Root
+	if root == "" {
+		root = u.Env.Root
+	}
+	u.normalizeName = relativeNamer(filepath.Join(root, u.DirName))
+	u.versions = make(map[string]string)
 
-	uploadFile := func(sourceFile string) {
-		defer func() {
-			wg.Done()
-			<-maxParallel
-		}()
-		version, err := d.uploadFile(sourceFile, u.EndPoint, u.Env, normalizeName)
+	var (
+		sourceFiles, ignoredFiles []string
+		err                       error
+	)
+	start := u.Env.Clock.Now()
+	if u.Checksums != nil {
+		u.checksums = u.Checksums
+		for name := range u.Checksums {
+			sourceFiles = append(sourceFiles, filepath.Join(root, u.DirName, filepath.FromSlash(name)))
+		}
+		sort.Strings(sourceFiles)
+	} else {
+		sourceFiles, ignoredFiles, err = d.getSourceFiles(filepath.Join(root, u.DirName), u.Suffixes, u.Env)
 		if err != nil {
-			wg.Error(err)
-			return
+			return err
 		}
-		mutex.Lock()
-		currentVersions[normalizeName(sourceFile)] = version
-		defer mutex.Unlock()
+		start = endPhase(u.Env, &timings.Walk, start)
+		if checksums, ok := d.precomputed[u.DirName]; ok {
+			u.checksums = checksums
+		} else {
+			u.checksums, err = d.computeChecksums(sourceFiles, u.normalizeName)
+			if err != nil {
+				return err
+			}
+		}
+		endPhase(u.Env, &timings.Hash, start)
 	}
-	changed := false
-	var changedFiles []string
+
 	for _, sourceFile := range sourceFiles {
 		if !d.Force { // if not forced, verify changed content using checksums
-			name := normalizeName(sourceFile)
+			name := u.normalizeName(sourceFile)
 			var noUpload bool
 			if prevChecksum, ok := u.PrevChecksums[name]; ok {
-				noUpload = prevChecksum == currentChecksums[name]
+				noUpload = prevChecksum == u.checksums[name]
 			}
 			if prevVersion, ok := u.PrevVersions[name]; ok && noUpload {
-				currentVersions[name] = prevVersion
+				u.versions[name] = prevVersion
 				continue
 			}
 		}
-		changed = true
-		wg.Add(1)
-
-		changedFiles = append(changedFiles, sourceFile)
-
-		maxParallel <- struct{}{}
-		go uploadFile(sourceFile)
+		u.changedFiles = append(u.changedFiles, sourceFile)
 	}
-	if changed && d.Verbose {
-		var message string
-		switch u.DirName {
-		case "cloud":
-			message = "scripts"
-		case "public":
-			message = "hosting"
-		}
+
+	if d.Verbose && len(u.changedFiles) != 0 {
 		fmt.Fprintf(u.Env.Out,
 			`Uploading recent changes to %s...
 The following files will be uploaded:
 %s
 `,
-			message,
-			strings.Join(changedFiles, "\n"),
+			u.message(),
+			strings.Join(u.changedFiles, "\n"),
 		)
 		if len(ignoredFiles) != 0 {
 			fmt.Fprintln(u.Env.Out, "The following files will be ignored:")
@@ -293,10 +423,149 @@ The following files will be uploaded:
 			}
 		}
 	}
-	if err := wg.Wait(); err != nil {
+	return nil
+}
+
+// uploadJob is a file to upload for an uploader.
+type uploadJob struct {
+	uploader *uploader
+	file     string
+}
+
+type scriptJobsFirst []uploadJob
+
+func (s scriptJobsFirst) Len() int      { return len(s) }
+func (s scriptJobsFirst) Swap(i, j int) { s[i], s[j] = s[j], s[i] }
+func (s scriptJobsFirst) Less(i, j int) bool {
+	return s[i].uploader.EndPoint == scriptsTarget.EndPoint &&
+		s[j].uploader.EndPoint != scriptsTarget.EndPoint
+}
+
+// uploadTrees uploads the changed files of all the uploaders through a single
+// pool of d.concurrency() workers, scripts first so that a large hosting tree
+// does not delay them. The outcome of each uploader is recorded in it, an
+// error is returned only if the files of an uploader could not be listed for
+// another reason than a missing directory, or if all the directories are
+// missing.
+func (d *deployCmd) uploadTrees(e *parsecli.Env, uploaders []*uploader, timings *deployTimings) error {
+	var (
+		jobs     []uploadJob
+		size     int64
+		messages []string
+		missing  int
+	)
+	for _, u := range uploaders {
+		if u.err = d.prepareUpload(u, timings); u.err != nil {
+			if !stackerr.HasUnderlying(u.err, stackerr.MatcherFunc(os.IsNotExist)) {
+				return u.err
+			}
+			missing++
+			continue
+		}
+		if len(u.changedFiles) != 0 {
+			messages = append(messages, u.message())
+		}
+		for _, file := range u.changedFiles {
+			jobs = append(jobs, uploadJob{uploader: u, file: file})
+			if info, err := os.Stat(file); err == nil {
+				size += info.Size()
+			}
+		}
+	}
+	if missing == len(uploaders) && missing != 0 {
+		return uploaders[0].err
+	}
+	if len(jobs) == 0 {
+		return nil
+	}
+	sort.Stable(scriptJobsFirst(jobs))
+
+	start := e.Clock.Now()
+	transfer := e.Progress.Start("Uploading "+strings.Join(messages, " and "), len(jobs), size)
+	queue := make(chan uploadJob, len(jobs))
+	for _, job := range jobs {
+		queue <- job
+	}
+	close(queue)
+
+	var (
+		wg    sync.WaitGroup
+		mutex sync.Mutex
+	)
+	workers := d.concurrency()
+	if workers > len(jobs) {
+		workers = len(jobs)
+	}
+	wg.Add(workers)
+	for i := 0; i < workers; i++ {
+		go func() {
+			defer wg.Done()
+			for job := range queue {
+				u := job.uploader
+				version, err := d.uploadFileWithRetries(job.file, u.EndPoint, u.Env, u.normalizeName, transfer)
+				name := u.normalizeName(job.file)
+				mutex.Lock()
+				if err != nil {
+					u.failures = append(u.failures, uploadFailure{
+						Name:    path.Join(filepath.ToSlash(u.DirName), name),
+						Message: parsecli.ErrorString(u.Env, err),
+					})
+				} else {
+					u.versions[name] = version
+				}
+				mutex.Unlock()
+				if err == nil {
+					transfer.FileDone()
+					if u.Uploaded != nil {
+						u.Uploaded(name, u.checksums[name], version)
+					}
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	transfer.Finish()
+	endPhase(e, &timings.Upload, start)
+	return nil
+}
+
+// result returns the checksums and versions of the files of the uploader. The
+// versions uploaded so far are returned along with an *uploadError if some
+// files could not be uploaded, so they are not uploaded again.
+func (u *uploader) result() (map[string]string, map[string]string, error) {
+	if u.err != nil {
+		return nil, nil, u.err
+	}
+	if len(u.failures) != 0 {
+		return u.checksums, u.versions, &uploadError{Failures: u.failures}
+	}
+	return u.checksums, u.versions, nil
+}
+
+// uploadSourceFiles uploads the changed files of a single uploader.
+func (d *deployCmd) uploadSourceFiles(u *uploader) (map[string]string,
+	map[string]string, error) {
+	if err := d.uploadTrees(u.Env, []*uploader{u}, &deployTimings{}); err != nil {
 		return nil, nil, err
 	}
-	return currentChecksums, currentVersions, nil
+	return u.result()
+}
+
+// uploadFileWithRetries uploads a file, retrying transient failures with
+// exponential backoff.
+func (d *deployCmd) uploadFileWithRetries(filename, endpoint string, e *parsecli.Env,
+	normalizeName func(string) string, transfer *parsecli.Transfer) (string, error) {
+	wait := d.uploadWait
+	if wait == nil {
+		wait = uploadBackoff
+	}
+	for attempt := 1; ; attempt++ {
+		version, status, err := d.uploadFile(filename, endpoint, e, normalizeName, transfer)
+		if err == nil || attempt == maxUploadAttempts || !isTransientUploadError(err, status) {
+			return version, err
+		}
+		time.Sleep(wait(attempt))
+	}
 }
 
 type deployFileData struct {
@@ -354,11 +623,170 @@ func (d *deployCmd) getPrevDeplInfo(e *parsecli.Env) (*deployInfo, error) {
 	return prevDeplInfo, nil
 }
 
+// changeSet groups file names by how they differ from a previous release.
+type changeSet struct {
+	Added     []string
+	Modified  []string
+	Unchanged []string
+	Removed   []string
+}
+
+func (c *changeSet) changed() bool {
+	return len(c.Added) != 0 || len(c.Modified) != 0 || len(c.Removed) != 0
+}
+
+// diffChecksums compares checksums of the current files against the
+// checksums recorded for a previous release.
+func diffChecksums(prev, cur map[string]string) *changeSet {
+	var c changeSet
+	for name, checksum := range cur {
+		prevChecksum, ok := prev[name]
+		switch {
+		case !ok:
+			c.Added = append(c.Added, name)
+		case prevChecksum != checksum:
+			c.Modified = append(c.Modified, name)
+		default:
+			c.Unchanged = append(c.Unchanged, name)
+		}
+	}
+	for name := range prev {
+		if _, ok := cur[name]; !ok {
+			c.Removed = append(c.Removed, name)
+		}
+	}
+	sort.Strings(c.Added)
+	sort.Strings(c.Modified)
+	sort.Strings(c.Unchanged)
+	sort.Strings(c.Removed)
+	return &c
+}
+
+func (d *deployCmd) printChangeSet(c *changeSet, e *parsecli.Env) {
+	if len(c.Added)+len(c.Modified)+len(c.Unchanged)+len(c.Removed) == 0 {
+		fmt.Fprintln(e.Out, "  No files.")
+		return
+	}
+	for _, name := range c.Added {
+		fmt.Fprintf(e.Out, "  added:     %s\n", name)
+	}
+	for _, name := range c.Modified {
+		fmt.Fprintf(e.Out, "  modified:  %s\n", name)
+	}
+	for _, name := range c.Removed {
+		fmt.Fprintf(e.Out, "  removed:   %s\n", name)
+	}
+	if d.Verbose {
+		for _, name := range c.Unchanged {
+			fmt.Fprintf(e.Out, "  unchanged: %s\n", name)
+		}
+	} else if len(c.Unchanged) != 0 {
+		fmt.Fprintf(e.Out, "  unchanged: %d files\n", len(c.Unchanged))
+	}
+}
+
+// printPlan prints what a deploy would upload, without uploading any files or
+// creating a new release.
+func (d *deployCmd) printPlan(parseVersion string, e *parsecli.Env) error {
+	prevDeplInfo, err := d.getPrevDeplInfo(e)
+	if err != nil {
+		return err
+	}
+
+	if prevDeplInfo.ReleaseName != "" {
+		fmt.Fprintf(e.Out, "Comparing with release %s\n", prevDeplInfo.ReleaseName)
+	}
+	changed := false
+	for _, target := range deployTargets {
+		checksums, err := d.targetChecksums(target, e)
+		if err != nil {
+			return err
+		}
+		changes := diffChecksums(target.checksums(prevDeplInfo), checksums)
+		changed = changed || changes.changed()
+
+		fmt.Fprintf(e.Out, "Changes to %s (%s):\n", target.Message, target.Name)
+		d.printChangeSet(changes, e)
+	}
+
+	switch {
+	case parseVersion == "":
+		fmt.Fprintln(e.Out,
+			"JS SDK version not set, the latest available JS SDK version would be used")
+		changed = true
+	case parseVersion != prevDeplInfo.ParseVersion:
+		fmt.Fprintf(e.Out, "JS SDK version would change from %q to %q\n",
+			prevDeplInfo.ParseVersion, parseVersion)
+		changed = true
+	}
+
+	if d.Force {
+		fmt.Fprintln(e.Out, "All files would be uploaded because of --force")
+		changed = true
+	}
+	if changed {
+		fmt.Fprintln(e.Out, "A new release would be created")
+	} else {
+		fmt.Fprintln(e.Out, "No release would be created because no files have changed")
+	}
+	fmt.Fprintln(e.Out, "Dry run: nothing was uploaded")
+	return nil
+}
+
+// deployResult is the outcome of a deploy, printed on stdout with --json.
+type deployResult struct {
+	ReleaseName  string       `json:"releaseName"`
+	ParseVersion string       `json:"parseVersion"`
+	Created      bool         `json:"created"`
+	Warning      string       `json:"warning,omitempty"`
+	Uploaded     deployedList `json:"uploaded"`
+}
+
+// deployedList lists file names by target.
+type deployedList struct {
+	Cloud  []string `json:"cloud"`
+	Public []string `json:"public"`
+}
+
+// newDeployResult returns the result of a deploy that ended with the given
+// release, the files whose versions changed since the previous release are
+// the ones that were uploaded.
+func newDeployResult(prev, release *deployInfo, created bool, warning string) *deployResult {
+	r := &deployResult{
+		ReleaseName:  release.ReleaseName,
+		ParseVersion: release.ParseVersion,
+		Created:      created,
+		Warning:      warning,
+		Uploaded:     deployedList{Cloud: []string{}, Public: []string{}},
+	}
+	for _, target := range deployTargets {
+		uploaded := &r.Uploaded.Cloud
+		if target.Name == hostingTarget.Name {
+			uploaded = &r.Uploaded.Public
+		}
+		for name, version := range target.versions(release) {
+			if target.versions(prev)[name] != version {
+				*uploaded = append(*uploaded, name)
+			}
+		}
+		sort.Strings(*uploaded)
+	}
+	return r
+}
+
 func (d *deployCmd) deploy(
 	parseVersion string,
 	prevDeplInfo *deployInfo,
 	forDevelop bool,
 	e *parsecli.Env) (*deployInfo, error) {
+	// with --json stdout only gets the result, everything else goes to stderr
+	out := e.Out
+	if d.JSON {
+		je := *e
+		je.Out = e.Err
+		e = &je
+	}
+
 	if parseVersion == "" {
 		fmt.Fprintln(e.Err,
 			"JS SDK version not set, setting it to latest available JS SDK version",
@@ -379,32 +807,85 @@ func (d *deployCmd) deploy(
 		}
 	}
 
-	scriptChecksums, scriptVersions, err := d.uploadSourceFiles(&uploader{
-		DirName: "cloud",
-		Suffixes: map[string]struct{}{
-			".js":   {},
-			".ejs":  {},
-			".jade": {},
-		},
-		EndPoint:      "scripts",
-		PrevChecksums: prevDeplInfo.Checksums.Cloud,
-		PrevVersions:  prevDeplInfo.Versions.Cloud,
-		Env:           e})
-	if err != nil && !os.IsNotExist(err) {
+	if d.source == nil && d.precomputed == nil {
+		d.cache = loadChecksumCache(e)
+		defer func() {
+			d.cache.save(e)
+			d.cache = nil
+		}()
+	}
+
+	if err := d.predeploy(forDevelop, e); err != nil {
 		return nil, err
 	}
 
-	hostedChecksums, hostedVersions, err := d.uploadSourceFiles(&uploader{
-		DirName:       "public",
-		Suffixes:      map[string]struct{}{},
-		EndPoint:      "hosted_files",
-		PrevChecksums: prevDeplInfo.Checksums.Public,
-		PrevVersions:  prevDeplInfo.Versions.Public,
-		Env:           e})
-	if err != nil && !os.IsNotExist(err) {
+	checksums := deployFileData{Cloud: map[string]string{}, Public: map[string]string{}}
+	versions := deployFileData{Cloud: map[string]string{}, Public: map[string]string{}}
+	deployed := &deployInfo{Checksums: checksums, Versions: versions}
+
+	dirs := d.sourceDirs()
+	if d.source != nil {
+		// archives keep the files of each target in a directory of the same name
+		dirs = defaultSourceDirs
+	}
+	uploaders := make([]*uploader, len(dirs))
+	for i, dir := range dirs {
+		prevChecksums, prevVersions := d.previousFiles(dir.Target, prevDeplInfo)
+		u := &uploader{
+			DirName:       dir.DirName,
+			Suffixes:      dir.Suffixes,
+			EndPoint:      dir.Target.EndPoint,
+			PrevChecksums: prevChecksums,
+			PrevVersions:  prevVersions,
+			Env:           e,
+		}
+		if d.state != nil {
+			target := dir.Target
+			u.Uploaded = func(name, checksum, version string) {
+				d.state.record(target, name, checksum, version)
+			}
+		}
+		if d.source != nil {
+			u.Root, u.Checksums = d.source.Dir, d.source.Manifest.checksums(dir.Target.Name)
+		}
+		uploaders[i] = u
+	}
+	var timings deployTimings
+	if err := d.uploadTrees(e, uploaders, &timings); err != nil {
 		return nil, err
 	}
 
+	var failures []uploadFailure
+	for i, dir := range dirs {
+		dirChecksums, dirVersions, err := uploaders[i].result()
+		d.recordUploaded(dir.Target, dirChecksums, dirVersions)
+		if uerr, ok := err.(*uploadError); ok {
+			failures = append(failures, uerr.Failures...)
+			continue
+		}
+		if err != nil && !stackerr.HasUnderlying(err, stackerr.MatcherFunc(os.IsNotExist)) {
+			return nil, err
+		}
+
+		targetChecksums, targetVersions := dir.Target.checksums(deployed), dir.Target.versions(deployed)
+		for name, checksum := range dirChecksums {
+			if _, ok := targetChecksums[name]; ok {
+				return nil, stackerr.Newf(
+					"%s/%s is deployed from more than one source directory.",
+					dir.Target.Name,
+					name,
+				)
+			}
+			targetChecksums[name] = checksum
+			targetVersions[name] = dirVersions[name]
+		}
+	}
+	if len(failures) != 0 {
+		return nil, &uploadError{Failures: failures}
+	}
+	scriptChecksums, scriptVersions := checksums.Cloud, versions.Cloud
+	hostedChecksums, hostedVersions := checksums.Public, versions.Public
+
 	if len(scriptChecksums) == 0 && len(hostedChecksums) == 0 {
 		return nil, stackerr.New("No files to upload")
 	}
@@ -421,12 +902,25 @@ func (d *deployCmd) deploy(
 	noDiff = noDiff && (parseVersion == prevDeplInfo.ParseVersion)
 
 	if noDiff {
+		if err := d.clearState(); err != nil {
+			return nil, err
+		}
 		if d.Verbose {
 			fmt.Fprintln(e.Out, "Not creating a release because no files have changed")
 		}
+		if d.timings {
+			fmt.Fprintf(e.Out, "Deploy phases: %s\n", &timings)
+		}
+		d.result = newDeployResult(prevDeplInfo, prevDeplInfo, false, "")
+		if err := d.printResult(out); err != nil {
+			return nil, err
+		}
 		return prevDeplInfo, nil
 	}
 
+	if forDevelop {
+		printDevelopChanges(e, prevDeplInfo, &checksums)
+	}
 	if parseVersion == "" {
 		parseVersion = prevDeplInfo.ParseVersion
 	}
@@ -438,7 +932,9 @@ func (d *deployCmd) deploy(
 		Description:  d.Description,
 	}
 
+	start := e.Clock.Now()
 	res, err := d.makeNewRelease(newDeployInfo, e)
+	endPhase(e, &timings.Release, start)
 	if err != nil {
 		if forDevelop {
 			// if the release failed but we are in develop mode, we want to return
@@ -449,15 +945,36 @@ func (d *deployCmd) deploy(
 		}
 		return nil, err
 	}
+	if err := d.clearState(); err != nil {
+		return nil, err
+	}
 
 	if forDevelop {
-		fmt.Fprintln(e.Out, "Your changes are now live.")
+		fmt.Fprintln(e.Out, "Release succeeded, your changes are now live.")
 	} else {
 		if res.Warning != "" {
 			fmt.Fprintln(e.Err, res.Warning)
 		}
 		fmt.Fprintf(e.Out, "New release is named %s (using Parse JavaScript SDK v%s)\n", res.ReleaseName, res.ParseVersion)
 	}
+	if d.timings {
+		fmt.Fprintf(e.Out, "Deploy phases: %s\n", &timings)
+	}
+
+	// the release exists at this point, so a failing hook does not fail the deploy
+	if err := d.hooks.run(e, postdeployHook, res.ReleaseName); err != nil {
+		fmt.Fprintf(e.Err, "Release %s was created, but:\n%s\n", res.ReleaseName, parsecli.ErrorString(e, err))
+	}
+
+	release := &deployInfo{
+		ReleaseName:  res.ReleaseName,
+		ParseVersion: res.ParseVersion,
+		Versions:     newDeployInfo.Versions,
+	}
+	d.result = newDeployResult(prevDeplInfo, release, true, res.Warning)
+	if err := d.printResult(out); err != nil {
+		return nil, err
+	}
 
 	return &deployInfo{
 		ParseVersion: res.ParseVersion,
@@ -466,6 +983,108 @@ func (d *deployCmd) deploy(
 	}, nil
 }
 
+// printResult prints the result of the deploy as JSON with --json.
+func (d *deployCmd) printResult(out io.Writer) error {
+	if !d.JSON {
+		return nil
+	}
+	return stackerr.Wrap(json.NewEncoder(out).Encode(d.result))
+}
+
+// previousFiles returns the checksums and versions of the files of the target
+// that were already uploaded, either in the previous release or by an earlier
+// attempt of this deploy.
+func (d *deployCmd) previousFiles(t deployTarget, prevDeplInfo *deployInfo) (map[string]string, map[string]string) {
+	if d.uploaded == nil {
+		return t.checksums(prevDeplInfo), t.versions(prevDeplInfo)
+	}
+	checksums, versions := make(map[string]string), make(map[string]string)
+	for _, info := range []*deployInfo{prevDeplInfo, d.uploaded} {
+		for name, checksum := range t.checksums(info) {
+			if version, ok := t.versions(info)[name]; ok {
+				checksums[name] = checksum
+				versions[name] = version
+			}
+		}
+	}
+	return checksums, versions
+}
+
+// recordUploaded remembers the versions of the files of the target that were
+// uploaded.
+func (d *deployCmd) recordUploaded(t deployTarget, checksums, versions map[string]string) {
+	if d.uploaded == nil {
+		d.uploaded = &deployInfo{
+			Checksums: deployFileData{Cloud: map[string]string{}, Public: map[string]string{}},
+			Versions:  deployFileData{Cloud: map[string]string{}, Public: map[string]string{}},
+		}
+	}
+	for name, version := range versions {
+		t.checksums(d.uploaded)[name] = checksums[name]
+		t.versions(d.uploaded)[name] = version
+	}
+}
+
+// clearState forgets the persisted uploads once they are part of a release.
+func (d *deployCmd) clearState() error {
+	if d.state == nil {
+		return nil
+	}
+	return d.state.clear()
+}
+
+// localDeployChecksums computes the checksums of all the local files that
+// would be deployed.
+func (d *deployCmd) localDeployChecksums(e *parsecli.Env) (*deployFileData, error) {
+	cloud, err := d.targetChecksums(scriptsTarget, e)
+	if err != nil {
+		return nil, err
+	}
+	public, err := d.targetChecksums(hostingTarget, e)
+	if err != nil {
+		return nil, err
+	}
+	return &deployFileData{Cloud: cloud, Public: public}, nil
+}
+
+// predeploy runs the predeploy hook once per deploy. In develop mode it runs
+// again only when the deployed files changed since it last ran. It does not
+// run for deploys of a release archive, which are not built from the project.
+func (d *deployCmd) predeploy(forDevelop bool, e *parsecli.Env) error {
+	if d.source != nil || len(d.hooks.commands(predeployHook)) == 0 {
+		return nil
+	}
+	if !forDevelop {
+		if d.predeployed {
+			return nil
+		}
+		if err := d.hooks.run(e, predeployHook, ""); err != nil {
+			return err
+		}
+		d.predeployed = true
+		return nil
+	}
+
+	checksums, err := d.localDeployChecksums(e)
+	if err != nil {
+		return err
+	}
+	if d.hookChecksums != nil && reflect.DeepEqual(checksums, d.hookChecksums) {
+		return d.hookErr
+	}
+	d.hookErr = d.hooks.run(e, predeployHook, "")
+	if d.hookErr != nil {
+		fmt.Fprintln(e.Err, parsecli.ErrorString(e, d.hookErr))
+	}
+	// include the files written by the hook, so it does not run again because
+	// of its own output
+	d.hookChecksums, err = d.localDeployChecksums(e)
+	if err != nil {
+		return err
+	}
+	return d.hookErr
+}
+
 func (d *deployCmd) handleError(
 	n int,
 	err, prevErr error,
@@ -507,10 +1126,96 @@ func (d *deployCmd) handleError(
 	return nil
 }
 
+// jsSDK returns the JS SDK version to deploy with, an archive deployed with
+// --from keeps the version it was released with.
+func (d *deployCmd) jsSDK(c *parsecli.Context) string {
+	if d.source != nil && d.source.Manifest.ParseVersion != "" {
+		return d.source.Manifest.ParseVersion
+	}
+	return c.Config.GetProjectConfig().Parse.JSSDK
+}
+
+// checkFlags reports flags that cannot be used together.
+func (d *deployCmd) checkFlags() error {
+	if d.JSON && d.DryRun {
+		return stackerr.New("--json cannot be used with --dry-run.")
+	}
+	if d.Concurrency < 0 {
+		return stackerr.New("--concurrency must be a positive number.")
+	}
+	return nil
+}
+
+// setup loads the source directories and hooks of the project, and opens the
+// archive deployed with --from. Releases of project files made from a git
+// checkout name the commit in their description. The returned function
+// removes the extracted archive.
+func (d *deployCmd) setup(e *parsecli.Env, c *parsecli.Context) (func(), error) {
+	sources, err := projectSourceDirs(c.Config.GetProjectConfig())
+	if err != nil {
+		return nil, err
+	}
+	d.sources = sources
+	d.hooks = newDeployHooks(c)
+
+	if d.From == "" {
+		if _, commit := parsecli.ParseCommitDescription(d.Description); commit == nil {
+			d.Description = parsecli.DescribeWithCommit(d.Description, parsecli.GitHeadCommit(e.Root))
+		}
+		return func() {}, nil
+	}
+	source, err := openReleaseArchive(d.From)
+	if err != nil {
+		return nil, err
+	}
+	d.source = source
+	return func() { source.remove() }, nil
+}
+
 func (d *deployCmd) run(e *parsecli.Env, c *parsecli.Context) error {
+	if err := d.checkFlags(); err != nil {
+		return err
+	}
+	cleanup, err := d.setup(e, c)
+	if err != nil {
+		return err
+	}
+	defer cleanup()
+
+	if d.DryRun {
+		return d.printPlan(d.jsSDK(c), e)
+	}
+	if err := d.confirm(e, c); err != nil {
+		return err
+	}
+	return d.runApp(e, c)
+}
+
+// confirm asks for confirmation before deploying to a protected app.
+func (d *deployCmd) confirm(e *parsecli.Env, c *parsecli.Context) error {
+	if d.JSON {
+		// keep stdout for the result
+		je := *e
+		je.Out = e.Err
+		e = &je
+	}
+	return parsecli.ConfirmProtectedApp(e, c, "deploy", d.Confirmed)
+}
+
+// runApp deploys to the app of the context, retrying failed deploys.
+func (d *deployCmd) runApp(e *parsecli.Env, c *parsecli.Context) error {
+	if c.AppConfig != nil {
+		state, uploaded, err := openUploadState(e, c.AppConfig.GetApplicationID())
+		if err != nil {
+			return err
+		}
+		defer state.close(e)
+		d.state, d.uploaded = state, uploaded
+	}
+
 	var prevErr error
 	for i := 0; i < d.Retries; i++ {
-		parseVersion := c.Config.GetProjectConfig().Parse.JSSDK
+		parseVersion := d.jsSDK(c)
 		newDeployInfo, err := d.deploy(parseVersion, nil, false, e)
 		if err == nil {
 			if parseVersion == "" && newDeployInfo != nil && newDeployInfo.ParseVersion != "" {
@@ -530,24 +1235,55 @@ func (d *deployCmd) run(e *parsecli.Env, c *parsecli.Context) error {
 
 func NewDeployCmd(e *parsecli.Env) *cobra.Command {
 	d := deployCmd{
-		Verbose: true,
-		Retries: 3,
-		wait:    func(n int) time.Duration { return time.Duration(n) * time.Second },
+		Verbose:     true,
+		Retries:     3,
+		Concurrency: maxOpenFD,
+		wait:        func(n int) time.Duration { return time.Duration(n) * time.Second },
 	}
 
 	cmd := &cobra.Command{
 		Use:   "deploy [app]",
 		Short: "Deploys a Parse App",
-		Long:  `Deploys the code to the given app.`,
-		Run:   parsecli.RunWithClient(e, d.run),
+		Long: `Deploys the code to the given app.
+
+With --from, the files of an archive written by "parse download --archive"
+are deployed exactly as listed in its manifest, instead of the project files.
+
+With --apps or --all, the project is deployed to several apps in parallel, and
+a summary of the deploy to each app is printed at the end.
+
+With --json, a single JSON object describing the release and the uploaded
+files is printed on stdout, and all other messages are printed on stderr.`,
+		Run: func(cmd *cobra.Command, args []string) {
+			d.timings = d.Verbose && cmd.Flag("verbose").Changed
+			if d.Apps != "" || d.AllApps {
+				parsecli.RunWithArgs(e, d.runApps)(cmd, args)
+				return
+			}
+			parsecli.RunWithClient(e, d.run)(cmd, args)
+		},
 	}
 	cmd.Flags().StringVarP(&d.Description, "description", "d", d.Description,
 		"Add an optional description to the deploy")
+	cmd.Flags().StringVar(&d.Apps, "apps", d.Apps,
+		"Deploy to the given comma separated apps instead of a single app")
+	cmd.Flags().BoolVar(&d.AllApps, "all", d.AllApps,
+		"Deploy to all the apps in the config")
 	cmd.Flags().BoolVarP(&d.Force, "force", "f", d.Force,
 		"Force deploy files even if their content is unchanged")
 	cmd.Flags().BoolVarP(&d.Verbose, "verbose", "v", d.Verbose,
 		"Control verbosity of cmd line logs")
 	cmd.Flags().IntVarP(&d.Retries, "retries", "n", d.Retries,
 		"Max number of retries to perform until first successful deploy")
+	cmd.Flags().BoolVar(&d.DryRun, "dry-run", d.DryRun,
+		"Print the files that would be uploaded without deploying them")
+	cmd.Flags().BoolVar(&d.JSON, "json", d.JSON,
+		"Print the result of the deploy as JSON on stdout, and all other messages on stderr")
+	cmd.Flags().IntVar(&d.Concurrency, "concurrency", d.Concurrency,
+		"Max number of files uploaded at the same time")
+	cmd.Flags().BoolVar(&d.Confirmed, parsecli.ConfirmProtectedFlag, d.Confirmed,
+		"Deploy to protected apps without asking for confirmation")
+	cmd.Flags().StringVar(&d.From, "from", d.From,
+		"Deploy the files of the given release archive instead of the project files")
 	return cmd
 }
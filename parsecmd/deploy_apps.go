@@ -0,0 +1,271 @@
+package parsecmd
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/ParsePlatform/parse-cli/parsecli"
+	"github.com/facebookgo/stackerr"
+)
+
+// maxParallelApps bounds how many apps are deployed to at the same time.
+const maxParallelApps = 4
+
+// lockedBuffer is a buffer safe for concurrent writes.
+type lockedBuffer struct {
+	mutex sync.Mutex
+	buf   bytes.Buffer
+}
+
+func (b *lockedBuffer) Write(p []byte) (int, error) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	return b.buf.Write(p)
+}
+
+// appDeploy is the deploy to one of the apps of a deploy to several apps.
+type appDeploy struct {
+	Name    string
+	Env     *parsecli.Env
+	Context *parsecli.Context
+	// Output collects the messages of the deploy, which are printed once it
+	// is done so that the output of different apps is not interleaved.
+	Output lockedBuffer
+	Result *deployResult
+	Err    error
+}
+
+// appNames returns the apps given with --apps or --all.
+func (d *deployCmd) appNames(config parsecli.Config) ([]string, error) {
+	if d.AllApps {
+		if d.Apps != "" {
+			return nil, stackerr.New("--apps cannot be used with --all.")
+		}
+		names := config.GetAppNames()
+		if len(names) == 0 {
+			return nil, stackerr.New("No apps are configured in the current directory.")
+		}
+		return names, nil
+	}
+
+	var names []string
+	seen := make(map[string]bool)
+	for _, name := range strings.Split(d.Apps, ",") {
+		name = strings.TrimSpace(name)
+		if name == "" || seen[name] {
+			continue
+		}
+		seen[name] = true
+		names = append(names, name)
+	}
+	if len(names) == 0 {
+		return nil, stackerr.New("No app names given to --apps.")
+	}
+	return names, nil
+}
+
+// forApp returns a command deploying to the app of the given context, sharing
+// the work already done for all the apps.
+func (d *deployCmd) forApp(c *parsecli.Context) *deployCmd {
+	return &deployCmd{
+		Description: d.Description,
+		Force:       d.Force,
+		Confirmed:   d.Confirmed,
+		Verbose:     d.Verbose,
+		timings:     d.timings,
+		Retries:     d.Retries,
+		Concurrency: d.Concurrency,
+		wait:        d.wait,
+		uploadWait:  d.uploadWait,
+		source:      d.source,
+		sources:     d.sources,
+		hooks:       newDeployHooks(c),
+		predeployed: d.predeployed,
+		precomputed: d.precomputed,
+	}
+}
+
+// precomputeChecksums computes the checksums of the project files once for
+// all the apps.
+func (d *deployCmd) precomputeChecksums(e *parsecli.Env) error {
+	d.cache = loadChecksumCache(e)
+	defer func() {
+		d.cache.save(e)
+		d.cache = nil
+	}()
+	d.precomputed = make(map[string]map[string]string)
+	for _, dir := range d.sourceDirs() {
+		root := filepath.Join(e.Root, dir.DirName)
+		files, _, err := d.getSourceFiles(root, dir.Suffixes, e)
+		if err != nil {
+			if stackerr.HasUnderlying(err, stackerr.MatcherFunc(os.IsNotExist)) {
+				continue
+			}
+			return err
+		}
+		checksums, err := d.computeChecksums(files, relativeNamer(root))
+		if err != nil {
+			return err
+		}
+		d.precomputed[dir.DirName] = checksums
+	}
+	return nil
+}
+
+// runApps deploys the project to the apps given with --apps or --all.
+func (d *deployCmd) runApps(e *parsecli.Env, args []string) error {
+	if len(args) != 0 {
+		return stackerr.Newf("unexpected arguments, apps are given with --apps: %v", args)
+	}
+	if err := d.checkFlags(); err != nil {
+		return err
+	}
+	if d.JSON {
+		return stackerr.New("--json cannot be used with --apps or --all.")
+	}
+
+	config, err := parsecli.ConfigFromDir(e.Root)
+	if err != nil {
+		return err
+	}
+	names, err := d.appNames(config)
+	if err != nil {
+		return err
+	}
+	// resolve all the apps first, so that a typo does not leave only some of
+	// them deployed
+	var apps []*appDeploy
+	deployed := make(map[string]string) // app names by application id
+	for _, name := range names {
+		ae, c, err := parsecli.NewAppContext(e, name)
+		if err != nil {
+			return err
+		}
+		// an alias and the app it links to share the state of their uploads,
+		// so they are deployed to once
+		id := c.AppConfig.GetApplicationID()
+		if other, ok := deployed[id]; ok {
+			fmt.Fprintf(e.Err, "Skipping %s, it is the same app as %s.\n", name, other)
+			continue
+		}
+		deployed[id] = name
+		apps = append(apps, &appDeploy{Name: name, Env: ae, Context: c})
+	}
+
+	cleanup, err := d.setup(e, apps[0].Context)
+	if err != nil {
+		return err
+	}
+	defer cleanup()
+
+	if d.DryRun {
+		for _, app := range apps {
+			fmt.Fprintf(e.Out, "Deploy to %s:\n", app.Name)
+			if err := d.printPlan(d.jsSDK(app.Context), app.Env); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	for _, app := range apps {
+		if err := d.confirm(e, app.Context); err != nil {
+			return err
+		}
+	}
+
+	// the predeploy hook builds the files deployed to every app, so it runs
+	// once without an app name
+	d.hooks = &deployHooks{Config: d.hooks.Config}
+	if err := d.predeploy(false, e); err != nil {
+		return err
+	}
+	if d.jsSDK(apps[0].Context) == "" {
+		fmt.Fprintln(e.Err,
+			"JS SDK version not set, setting it to latest available JS SDK version",
+		)
+		if err := UseLatestJSSDK(apps[0].Env); err != nil {
+			return err
+		}
+		config, err := parsecli.ConfigFromDir(e.Root)
+		if err != nil {
+			return err
+		}
+		for _, app := range apps {
+			app.Context.Config.GetProjectConfig().Parse.JSSDK = config.GetProjectConfig().Parse.JSSDK
+		}
+	}
+	if d.source == nil {
+		if err := d.precomputeChecksums(e); err != nil {
+			return err
+		}
+	}
+
+	var (
+		wg          sync.WaitGroup
+		outputMutex sync.Mutex
+	)
+	maxParallel := make(chan struct{}, maxParallelApps)
+	wg.Add(len(apps))
+	for _, app := range apps {
+		maxParallel <- struct{}{}
+		go func(app *appDeploy) {
+			defer func() {
+				wg.Done()
+				<-maxParallel
+			}()
+			app.Env.Out = &app.Output
+			app.Env.Err = &app.Output
+			// progress lines of several apps would overwrite each other
+			app.Env.Progress = nil
+
+			ad := d.forApp(app.Context)
+			app.Err = ad.runApp(app.Env, app.Context)
+			app.Result = ad.result
+
+			outputMutex.Lock()
+			defer outputMutex.Unlock()
+			fmt.Fprintf(e.Out, "Deploy to %s:\n%s\n", app.Name, app.Output.buf.String())
+		}(app)
+	}
+	wg.Wait()
+
+	return printAppsSummary(e, apps)
+}
+
+// printAppsSummary prints how the deploy to each app ended.
+func printAppsSummary(e *parsecli.Env, apps []*appDeploy) error {
+	width := 0
+	for _, app := range apps {
+		if len(app.Name) > width {
+			width = len(app.Name)
+		}
+	}
+
+	failed := 0
+	fmt.Fprintln(e.Out, "Deploy summary:")
+	for _, app := range apps {
+		var status string
+		switch {
+		case app.Err != nil:
+			failed++
+			status = "failed: " + strings.Replace(
+				parsecli.ErrorString(app.Env, app.Err), "\n", "\n    ", -1)
+		case app.Result == nil:
+			status = "deployed"
+		case app.Result.Created:
+			status = "created release " + app.Result.ReleaseName
+		default:
+			status = fmt.Sprintf("no changes, release %s is current", app.Result.ReleaseName)
+		}
+		fmt.Fprintf(e.Out, "  %-*s %s\n", width+1, app.Name+":", status)
+	}
+
+	if failed != 0 {
+		return stackerr.Newf("Deploy failed for %d of %d apps.", failed, len(apps))
+	}
+	return nil
+}
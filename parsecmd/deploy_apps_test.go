@@ -0,0 +1,138 @@
+package parsecmd
+
+import (
+	"io/ioutil"
+	"net/http"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/ParsePlatform/parse-cli/parsecli"
+	"github.com/facebookgo/ensure"
+)
+
+// newAppsHarness configures the staging, qa and demo apps in a deploy
+// harness, and counts the uploads to each app. Creating a release for the
+// apps in failing fails.
+func newAppsHarness(t *testing.T, failing string) (*parsecli.Harness, map[string]int) {
+	h := setupForDeploy(t, &deployInfo{ReleaseName: "v2", ParseVersion: "1.2.9"})
+	ensure.Nil(t, ioutil.WriteFile(
+		filepath.Join(h.Env.Root, parsecli.LegacyConfigFile),
+		[]byte(`{
+  "global": {"parseVersion": "1.2.9"},
+  "applications": {
+    "staging": {"applicationId": "staging-id", "masterKey": "key"},
+    "qa": {"applicationId": "qa-id", "masterKey": "key"},
+    "demo": {"applicationId": "demo-id", "masterKey": "key"},
+    "_default": {"link": "staging"}
+  }
+}`),
+		0600,
+	))
+
+	var mutex sync.Mutex
+	uploads := make(map[string]int)
+	ht := h.Env.ParseAPIClient.APIClient.Transport
+	h.Env.ParseAPIClient.APIClient.Transport = parsecli.TransportFunc(
+		func(r *http.Request) (*http.Response, error) {
+			app := r.Header.Get("X-Parse-Application-Id")
+			switch {
+			case r.URL.Path == "/1/scripts" || r.URL.Path == "/1/hosted_files":
+				mutex.Lock()
+				uploads[app]++
+				mutex.Unlock()
+			case r.URL.Path == "/1/deploy" && r.Method == "POST" && strings.Contains(failing, app):
+				return &http.Response{
+					StatusCode: http.StatusBadRequest,
+					Body:       ioutil.NopCloser(strings.NewReader(`{"error": "release rejected"}`)),
+				}, nil
+			}
+			return ht.RoundTrip(r)
+		})
+	return h, uploads
+}
+
+func TestDeployApps(t *testing.T) {
+	t.Parallel()
+	h, uploads := newAppsHarness(t, "qa-id")
+	defer h.Stop()
+
+	d := &deployCmd{Apps: "staging, qa,staging", Retries: 1}
+	err := d.runApps(h.Env, nil)
+	ensure.Err(t, err, regexp.MustCompile(`Deploy failed for 1 of 2 apps.`))
+	ensure.DeepEqual(t, uploads, map[string]int{"staging-id": 2, "qa-id": 2})
+	ensure.StringContains(t, h.Out.String(), "Deploy to staging:\n")
+	ensure.StringContains(t, h.Out.String(), `Deploy summary:
+  staging: created release v2
+  qa:      failed: release rejected
+`)
+}
+
+func TestDeployAppsAlias(t *testing.T) {
+	t.Parallel()
+	h, uploads := newAppsHarness(t, "")
+	defer h.Stop()
+
+	d := &deployCmd{Apps: "staging,_default,qa", Retries: 1}
+	ensure.Nil(t, d.runApps(h.Env, nil))
+	ensure.DeepEqual(t, uploads, map[string]int{"staging-id": 2, "qa-id": 2})
+	ensure.StringContains(t, h.Err.String(), "Skipping _default, it is the same app as staging.\n")
+	ensure.StringContains(t, h.Out.String(), `Deploy summary:
+  staging: created release v2
+  qa:      created release v2
+`)
+}
+
+func TestDeployAllApps(t *testing.T) {
+	t.Parallel()
+	h, uploads := newAppsHarness(t, "")
+	defer h.Stop()
+
+	d := &deployCmd{AllApps: true, Retries: 1}
+	ensure.Nil(t, d.runApps(h.Env, nil))
+	ensure.DeepEqual(t, uploads, map[string]int{"staging-id": 2, "qa-id": 2, "demo-id": 2})
+	ensure.DeepEqual(t, len(d.precomputed), 2)
+	ensure.StringContains(t, h.Out.String(), `Deploy summary:
+  demo:    created release v2
+  qa:      created release v2
+  staging: created release v2
+`)
+}
+
+func TestDeployAppsUnknownApp(t *testing.T) {
+	t.Parallel()
+	h, uploads := newAppsHarness(t, "")
+	defer h.Stop()
+
+	d := &deployCmd{Apps: "staging,prod", Retries: 1}
+	ensure.Err(t, d.runApps(h.Env, nil), regexp.MustCompile(`App "prod" wasn't found.`))
+	ensure.DeepEqual(t, len(uploads), 0)
+}
+
+func TestDeployAppsProtected(t *testing.T) {
+	t.Parallel()
+	h, uploads := newAppsHarness(t, "")
+	defer h.Stop()
+	ensure.Nil(t, ioutil.WriteFile(
+		filepath.Join(h.Env.Root, parsecli.LegacyConfigFile),
+		[]byte(`{
+  "global": {"parseVersion": "1.2.9"},
+  "applications": {
+    "staging": {"applicationId": "staging-id", "masterKey": "key"},
+    "production": {"applicationId": "production-id", "masterKey": "key", "protected": true}
+  }
+}`),
+		0600,
+	))
+
+	h.Env.In = strings.NewReader("prod\n")
+	d := &deployCmd{AllApps: true, Retries: 1}
+	ensure.Err(t, d.runApps(h.Env, nil), regexp.MustCompile(`The deploy was not confirmed`))
+	ensure.DeepEqual(t, len(uploads), 0)
+
+	h.Env.In = strings.NewReader("production\n")
+	ensure.Nil(t, d.runApps(h.Env, nil))
+	ensure.DeepEqual(t, uploads, map[string]int{"staging-id": 2, "production-id": 2})
+}
@@ -175,7 +175,7 @@ func TestUploadFileNoFile(t *testing.T) {
 	defer h.Stop()
 
 	var d deployCmd
-	_, err := d.uploadFile("cloud/master.js", "", h.Env, nil)
+	_, _, err := d.uploadFile("cloud/master.js", "", h.Env, nil, nil)
 	switch runtime.GOOS {
 	case "windows":
 		ensure.Err(t, err, regexp.MustCompile(`The system cannot find the path specified.`))
@@ -199,8 +199,8 @@ func TestUploadFileHttpError(t *testing.T) {
 	})
 	h.Env.ParseAPIClient = &parsecli.ParseAPIClient{APIClient: &parse.Client{Transport: ht}}
 	dirRoot := filepath.Join(h.Env.Root, "cloud")
-	_, err := d.uploadFile(filepath.Join(dirRoot, "main.js"), "uploads",
-		h.Env, func(name string) string { return "main.js" })
+	_, _, err := d.uploadFile(filepath.Join(dirRoot, "main.js"), "uploads",
+		h.Env, func(name string) string { return "main.js" }, nil)
 	ensure.Err(t, err, regexp.MustCompile("something is wrong"))
 }
 
@@ -220,8 +220,8 @@ func TestUploadFileMalformed(t *testing.T) {
 
 	h.Env.ParseAPIClient = &parsecli.ParseAPIClient{APIClient: &parse.Client{Transport: ht}}
 	dirRoot := filepath.Join(h.Env.Root, "cloud")
-	_, err := d.uploadFile(filepath.Join(dirRoot, "main.js"), "uploads", h.Env,
-		func(name string) string { return "main.js" })
+	_, _, err := d.uploadFile(filepath.Join(dirRoot, "main.js"), "uploads", h.Env,
+		func(name string) string { return "main.js" }, nil)
 	ensure.Err(t, err, regexp.MustCompile(`Malformed response when trying to upload `))
 }
 
@@ -545,6 +545,102 @@ Not creating a release because no files have changed
 `)
 }
 
+func TestDeployTimings(t *testing.T) {
+	t.Parallel()
+	info := &deployInfo{
+		ParseVersion: "latest",
+		Checksums: deployFileData{
+			Cloud:  map[string]string{"main.js": "4ece160cc8e5e828ee718e7367cf5d37"},
+			Public: map[string]string{"index.html": "9e2354a0ebac5852bc674026137c8612"},
+		},
+		Versions: deployFileData{
+			Cloud:  map[string]string{"main.js": "f2"},
+			Public: map[string]string{"index.html": "f2"},
+		},
+	}
+
+	h := setupForDeploy(t, info)
+	defer h.Stop()
+
+	d := deployCmd{timings: true}
+	_, err := d.deploy("latest", nil, false, h.Env)
+	ensure.Nil(t, err)
+	ensure.DeepEqual(t, h.Out.String(), "Deploy phases: walk 0s, hash 0s, upload 0s\n")
+}
+
+func TestDeployWithoutHostingDir(t *testing.T) {
+	t.Parallel()
+	info := &deployInfo{ReleaseName: "v1", ParseVersion: "latest"}
+
+	h := setupForDeploy(t, info)
+	defer h.Stop()
+	ensure.Nil(t, os.RemoveAll(filepath.Join(h.Env.Root, parsecli.HostingDir)))
+
+	d := deployCmd{}
+	res, err := d.deploy("latest", nil, false, h.Env)
+	ensure.Nil(t, err)
+	ensure.DeepEqual(t, res.Checksums.Cloud, map[string]string{"main.js": "4ece160cc8e5e828ee718e7367cf5d37"})
+	ensure.DeepEqual(t, len(res.Checksums.Public), 0)
+	ensure.StringContains(t, h.Out.String(), "New release is named v1")
+}
+
+func TestDeployJSON(t *testing.T) {
+	t.Parallel()
+	info := &deployInfo{
+		ReleaseName:  "v1",
+		ParseVersion: "latest",
+		Checksums: deployFileData{
+			Cloud:  map[string]string{"main.js": "d41d8cd98f00b204e9800998ecf8427e"},
+			Public: map[string]string{"index.html": "9e2354a0ebac5852bc674026137c8612"},
+		},
+		Versions: deployFileData{
+			Cloud:  map[string]string{"main.js": "f1"},
+			Public: map[string]string{"index.html": "f2"},
+		},
+		Warning: "Cloud Code is deprecated",
+	}
+
+	h := setupForDeploy(t, info)
+	defer h.Stop()
+
+	d := deployCmd{Verbose: true, JSON: true}
+	_, err := d.deploy("latest", nil, false, h.Env)
+	ensure.Nil(t, err)
+	ensure.DeepEqual(t, h.Out.String(),
+		`{"releaseName":"v1","parseVersion":"latest","created":true,"warning":"Cloud Code is deprecated",`+
+			`"uploaded":{"cloud":["main.js"],"public":[]}}
+`)
+	ensure.StringContains(t, h.Err.String(), "Uploading recent changes to scripts...")
+	ensure.StringContains(t, h.Err.String(), "New release is named v1")
+}
+
+func TestDeployJSONUnchanged(t *testing.T) {
+	t.Parallel()
+	info := &deployInfo{
+		ReleaseName:  "v1",
+		ParseVersion: "latest",
+		Checksums: deployFileData{
+			Cloud:  map[string]string{"main.js": "4ece160cc8e5e828ee718e7367cf5d37"},
+			Public: map[string]string{"index.html": "9e2354a0ebac5852bc674026137c8612"},
+		},
+		Versions: deployFileData{
+			Cloud:  map[string]string{"main.js": "f2"},
+			Public: map[string]string{"index.html": "f2"},
+		},
+	}
+
+	h := setupForDeploy(t, info)
+	defer h.Stop()
+
+	d := deployCmd{Verbose: true, JSON: true}
+	_, err := d.deploy("latest", nil, false, h.Env)
+	ensure.Nil(t, err)
+	ensure.DeepEqual(t, h.Out.String(),
+		`{"releaseName":"v1","parseVersion":"latest","created":false,"uploaded":{"cloud":[],"public":[]}}
+`)
+	ensure.StringContains(t, h.Err.String(), "Not creating a release because no files have changed")
+}
+
 func TestDeployFilesNoVersion(t *testing.T) {
 	t.Parallel()
 	info := &deployInfo{
@@ -713,3 +809,68 @@ func TestIgnoredFilesUnderDotDir(t *testing.T) {
 			filepath.Join(h.Env.Root, parsecli.HostingDir, ".ignore"),
 		})
 }
+
+func TestDeployDryRun(t *testing.T) {
+	t.Parallel()
+	info := &deployInfo{
+		ReleaseName:  "v1",
+		ParseVersion: "latest",
+		Checksums: deployFileData{
+			Cloud: map[string]string{
+				"main.js": "d41d8cd98f00b204e9800998ecf8427e",
+				"old.js":  "d41d8cd98f00b204e9800998ecf8427e",
+			},
+			Public: map[string]string{"index.html": "9e2354a0ebac5852bc674026137c8612"},
+		},
+		Versions: deployFileData{
+			Cloud: map[string]string{
+				"main.js": "f1",
+				"old.js":  "f1",
+			},
+			Public: map[string]string{"index.html": "f1"},
+		},
+	}
+
+	h := createParseProject(t)
+	defer h.Stop()
+
+	ht := parsecli.TransportFunc(func(r *http.Request) (*http.Response, error) {
+		ensure.DeepEqual(t, r.URL.Path, "/1/deploy")
+		ensure.DeepEqual(t, r.Method, "GET")
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Body:       ioutil.NopCloser(strings.NewReader(jsonStr(t, info))),
+		}, nil
+	})
+	h.Env.ParseAPIClient = &parsecli.ParseAPIClient{APIClient: &parse.Client{Transport: ht}}
+
+	d := deployCmd{Verbose: true, DryRun: true}
+	ctx := parsecli.Context{Config: defaultParseConfig}
+	ctx.Config.GetProjectConfig().Parse.JSSDK = "latest"
+	ensure.Nil(t, d.run(h.Env, &ctx))
+	ensure.DeepEqual(t, h.Out.String(), `Comparing with release v1
+Changes to scripts (cloud):
+  modified:  main.js
+  removed:   old.js
+Changes to hosting (public):
+  unchanged: index.html
+A new release would be created
+Dry run: nothing was uploaded
+`)
+}
+
+func TestDiffChecksums(t *testing.T) {
+	t.Parallel()
+	changes := diffChecksums(
+		map[string]string{"a": "1", "b": "2", "c": "3"},
+		map[string]string{"a": "1", "b": "4", "d": "5"},
+	)
+	ensure.DeepEqual(t, changes, &changeSet{
+		Added:     []string{"d"},
+		Modified:  []string{"b"},
+		Unchanged: []string{"a"},
+		Removed:   []string{"c"},
+	})
+	ensure.True(t, changes.changed())
+	ensure.False(t, diffChecksums(nil, nil).changed())
+}
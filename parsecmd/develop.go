@@ -3,9 +3,14 @@ package parsecmd
 import (
 	"fmt"
 	"net"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
 	"time"
 
 	"github.com/ParsePlatform/parse-cli/parsecli"
+	"github.com/facebookgo/clock"
 	"github.com/spf13/cobra"
 )
 
@@ -17,11 +22,19 @@ const (
 )
 
 type developCmd struct {
-	deployInterval time.Duration // The number of seconds between deploy
+	deployInterval time.Duration // The number of seconds between deploy when polling
+	poll           bool          // If set, files are polled for changes instead of watched
 	mustFetch      bool          // If set, prevDeployInfo will always be fetched from server
 	Verbose        bool          // If set, will print details about deploy in addition to server logs
+	Concurrency    int           // The max number of files uploaded at the same time
+	NotifyCommand  string        // Run instead of ringing the bell when a deploy starts failing
+	sources        sourceDirList // The directories deployed, the default ones if nil
 }
 
+// maxDevelopChanges bounds how many changed files are listed after each
+// change in develop mode.
+const maxDevelopChanges = 10
+
 type deployFunc func(parseVersion string,
 	prevDeplInfo *deployInfo,
 	forDevelop bool,
@@ -32,14 +45,51 @@ func (d *developCmd) contDeploy(e *parsecli.Env, deployer deployFunc, first, don
 		d.deployInterval = time.Second
 	}
 
-	var prevDeplInfo *deployInfo
-	ticker := e.Clock.Ticker(d.deployInterval)
-	defer ticker.Stop()
+	var (
+		prevDeplInfo *deployInfo
+		tick         <-chan time.Time
+		changed      <-chan struct{}
+	)
+	var ticker *clock.Ticker
+	poll := func() {
+		if ticker == nil {
+			ticker = e.Clock.Ticker(d.deployInterval)
+			tick = ticker.C
+		}
+	}
+	stopPolling := func() {
+		if ticker != nil {
+			ticker.Stop()
+			ticker, tick = nil, nil
+		}
+	}
+	defer stopPolling()
+	w := d.watch(e)
+	if w != nil {
+		defer w.Close()
+		changed = debounce(e.Clock, w.Changes(), watchQuiet, true, done)
+	} else {
+		poll()
+	}
 
+	var status developStatus
 	latestError := false
 	for {
 		select {
-		case <-ticker.C:
+		case <-tick:
+		case _, ok := <-changed:
+			if !ok {
+				// changes made while the watcher stopped are picked up by
+				// deploying right away
+				fmt.Fprintf(
+					e.Err,
+					"Stopped watching files for changes, polling every %s instead:\n%s\n",
+					d.deployInterval,
+					parsecli.ErrorString(e, w.Err()),
+				)
+				changed = nil
+				poll()
+			}
 		case <-done:
 			return
 		}
@@ -64,7 +114,20 @@ Please fix your config file in %s and try again.
 			continue
 		}
 		latestError = false
-		newDeplInfo, _ := deployer(config.GetProjectConfig().Parse.JSSDK, prevDeplInfo, true, e)
+		newDeplInfo, err := deployer(config.GetProjectConfig().Parse.JSSDK, prevDeplInfo, true, e)
+		// without changes since the previous deploy, the previous release is
+		// returned as is, which does not show that the project deploys
+		released := prevDeplInfo == nil || newDeplInfo != prevDeplInfo
+		d.report(e, &status, released, err)
+		if changed != nil {
+			// while watching, a failed deploy is retried every interval until
+			// one succeeds, as it may have failed on the network
+			if err != nil {
+				poll()
+			} else {
+				stopPolling()
+			}
+		}
 		if !d.mustFetch {
 			prevDeplInfo = newDeplInfo
 		}
@@ -75,6 +138,141 @@ Please fix your config file in %s and try again.
 	}
 }
 
+// developChange is a file changed since the previous release.
+type developChange struct {
+	Name string
+	Kind string
+}
+
+type byDevelopChangeName []developChange
+
+func (b byDevelopChangeName) Len() int           { return len(b) }
+func (b byDevelopChangeName) Swap(i, j int)      { b[i], b[j] = b[j], b[i] }
+func (b byDevelopChangeName) Less(i, j int) bool { return b[i].Name < b[j].Name }
+
+// printDevelopChanges lists the files whose checksums changed since the
+// previous release.
+func printDevelopChanges(e *parsecli.Env, prev *deployInfo, checksums *deployFileData) {
+	current := &deployInfo{Checksums: *checksums}
+	var changes []developChange
+	for _, target := range deployTargets {
+		before, after := target.checksums(prev), target.checksums(current)
+		for name, checksum := range after {
+			if prevChecksum, ok := before[name]; !ok {
+				changes = append(changes, developChange{target.Name + "/" + name, "added"})
+			} else if prevChecksum != checksum {
+				changes = append(changes, developChange{target.Name + "/" + name, "modified"})
+			}
+		}
+		for name := range before {
+			if _, ok := after[name]; !ok {
+				changes = append(changes, developChange{target.Name + "/" + name, "removed"})
+			}
+		}
+	}
+	if len(changes) == 0 {
+		return
+	}
+	sort.Sort(byDevelopChangeName(changes))
+
+	fmt.Fprintln(e.Out, "Changed files:")
+	for i, change := range changes {
+		if i == maxDevelopChanges {
+			fmt.Fprintf(e.Out, "  and %d more\n", len(changes)-i)
+			break
+		}
+		fmt.Fprintf(e.Out, "  %-8s %s\n", change.Kind, change.Name)
+	}
+}
+
+// developStatus is how the deploys of develop ended so far.
+type developStatus struct {
+	green bool // set when the last deploy succeeded
+}
+
+// report prints the error of a failed deploy, and alerts the user when a
+// deploy which succeeded starts failing. Later failures are printed without
+// alerting again, even when the error changes. A deploy that neither created
+// nor fetched a release leaves the status unchanged.
+func (d *developCmd) report(e *parsecli.Env, s *developStatus, released bool, err error) {
+	if err == nil {
+		if released {
+			s.green = true
+		}
+		return
+	}
+	message := parsecli.ErrorString(e, err)
+	fmt.Fprintf(e.Err, "Deploy failed:\n  %s\n", strings.Replace(message, "\n", "\n  ", -1))
+	if s.green {
+		s.green = false
+		d.alert(e, message)
+	}
+}
+
+// alert rings the terminal bell, or runs the notify command with the error in
+// the environment.
+func (d *developCmd) alert(e *parsecli.Env, message string) {
+	if d.NotifyCommand == "" {
+		fmt.Fprint(e.Err, "\a")
+		return
+	}
+	cmd := shellCommand(d.NotifyCommand)
+	cmd.Dir = e.Root
+	cmd.Stdout = e.Out
+	cmd.Stderr = e.Err
+	cmd.Env = append(
+		os.Environ(),
+		"PARSE_DEVELOP_ERROR="+message,
+		"PARSE_ROOT="+e.Root,
+	)
+	if err := cmd.Run(); err != nil {
+		fmt.Fprintf(e.Err, "The notify command %q failed: %s\n", d.NotifyCommand, err)
+	}
+}
+
+// watch returns a watcher for the project files, or nil if they have to be
+// polled for changes.
+func (d *developCmd) watch(e *parsecli.Env) watcher {
+	if d.poll {
+		return nil
+	}
+	dirs, files := d.watchedPaths(e)
+	w, err := newWatcher(dirs, files)
+	if err != nil {
+		if err != errWatchUnsupported {
+			fmt.Fprintf(
+				e.Err,
+				"Unable to watch files for changes, polling every %s instead:\n%s\n",
+				d.deployInterval,
+				parsecli.ErrorString(e, err),
+			)
+		}
+		return nil
+	}
+	return w
+}
+
+// watchedPaths returns the directories deployed from, and the files whose
+// changes affect deploys.
+func (d *developCmd) watchedPaths(e *parsecli.Env) (dirs, files []string) {
+	sources := d.sources
+	if sources == nil {
+		sources = defaultSourceDirs
+	}
+	for _, source := range sources {
+		dirs = append(dirs, filepath.Join(e.Root, source.DirName))
+	}
+	for _, name := range []string{
+		parsecli.ParseLocal,
+		parsecli.ParseProject,
+		parsecli.LegacyConfigFile,
+		parseIgnore,
+	} {
+		files = append(files, filepath.Join(e.Root, name))
+	}
+	return dirs, files
+}
+
 func (d *developCmd) handleError(e *parsecli.Env, err error, sleep func(time.Duration)) error {
 	if err == nil {
 		return nil
@@ -94,14 +292,25 @@ func (d *developCmd) handleError(e *parsecli.Env, err error, sleep func(time.Dur
 }
 
 func (d *developCmd) run(e *parsecli.Env, c *parsecli.Context) error {
+	sources, err := projectSourceDirs(c.Config.GetProjectConfig())
+	if err != nil {
+		return err
+	}
+	d.sources = sources
+
 	first := make(chan struct{})
 	go d.contDeploy(e,
-		deployFunc((&deployCmd{Verbose: d.Verbose}).deploy),
+		deployFunc((&deployCmd{
+			Verbose:     d.Verbose,
+			timings:     d.Verbose,
+			Concurrency: d.Concurrency,
+			sources:     sources,
+			hooks:       newDeployHooks(c),
+		}).deploy),
 		first,
 		make(chan struct{}))
 	<-first
 
-	var err error
 	for i := 0; i < maxLogRetries; i++ {
 		l := &logsCmd{num: 1, level: "INFO"}
 
@@ -123,19 +332,28 @@ func (d *developCmd) run(e *parsecli.Env, c *parsecli.Context) error {
 }
 
 func NewDevelopCmd(e *parsecli.Env) *cobra.Command {
-	d := &developCmd{deployInterval: time.Second}
+	d := &developCmd{deployInterval: time.Second, Concurrency: maxOpenFD}
 	cmd := &cobra.Command{
 		Use:   "develop app",
 		Short: "Monitors for changes to code and deploys, also tails parse logs",
 		Long: `Monitors for changes to source files and uploads updated files to Parse. ` +
+			`Changes are detected with file system notifications where supported, ` +
+			`files are polled for changes every --interval otherwise or with --poll. ` +
+			`The terminal bell rings when a deploy which succeeded starts failing, ` +
+			`or --notify-command runs instead. ` +
 			`This will also monitor the parse INFO log for any new log messages and write ` +
 			`out updates to the terminal. This requires an app to be provided, to ` +
 			`avoid running develop on production apps accidently.`,
 		Run: parsecli.RunWithClientConfirm(e, d.run),
 	}
-	cmd.Flags().DurationVarP(&d.deployInterval, "interval", "i", d.deployInterval, "Number of seconds between deploys.")
+	cmd.Flags().DurationVarP(&d.deployInterval, "interval", "i", d.deployInterval, "Number of seconds between deploys when polling.")
+	cmd.Flags().BoolVar(&d.poll, "poll", d.poll, "Poll files for changes instead of watching them")
 	cmd.Flags().BoolVarP(&d.mustFetch, "fetch", "f", d.mustFetch, "Always fetch previous deployment info from server")
 	cmd.Flags().BoolVarP(&d.Verbose, "verbose", "v", d.Verbose, "Control verbosity of cmd line logs")
+	cmd.Flags().IntVar(&d.Concurrency, "concurrency", d.Concurrency, "Max number of files uploaded at the same time")
+	cmd.Flags().StringVar(&d.NotifyCommand, "notify-command", d.NotifyCommand,
+		"Command run instead of ringing the bell when a deploy starts failing, "+
+			"the error is in $PARSE_DEVELOP_ERROR")
 
 	return cmd
 }
@@ -6,6 +6,8 @@ import (
 	"io/ioutil"
 	"net"
 	"path/filepath"
+	"runtime"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -33,7 +35,7 @@ func TestContDeploy(t *testing.T) {
 	}()
 
 	first := make(chan struct{})
-	(&developCmd{}).contDeploy(h.Env, deployer, first, done)
+	(&developCmd{poll: true}).contDeploy(h.Env, deployer, first, done)
 	_, opened := <-first
 	ensure.False(t, opened)
 }
@@ -67,7 +69,7 @@ func TestContDeployConfigErr(t *testing.T) {
 	}()
 
 	first := make(chan struct{})
-	(&developCmd{}).contDeploy(h.Env, deployer, first, done)
+	(&developCmd{poll: true}).contDeploy(h.Env, deployer, first, done)
 	_, opened := <-first
 	ensure.False(t, opened)
 
@@ -83,6 +85,152 @@ Please fix your config file in %s and try again.
 	)
 }
 
+func TestContDeployWatch(t *testing.T) {
+	t.Parallel()
+
+	h := createParseProject(t)
+	defer h.Stop()
+
+	deploys := make(chan struct{}, 10)
+	deployer := deployFunc(func(parseVersion string,
+		prevDeplInfo *deployInfo,
+		forDevelop bool,
+		e *parsecli.Env) (*deployInfo, error) {
+		deploys <- struct{}{}
+		return &deployInfo{}, nil
+	})
+
+	done := make(chan struct{})
+	first := make(chan struct{})
+	go (&developCmd{}).contDeploy(h.Env, deployer, first, done)
+	defer close(done)
+	<-first
+	<-deploys
+
+	ensure.Nil(t, ioutil.WriteFile(
+		filepath.Join(h.Env.Root, parsecli.CloudDir, "main.js"), []byte("changed"), 0600))
+	ensure.True(t, waitDebounced(t, h.Clock, deploys) >= watchQuiet)
+}
+
+func TestContDeployWatchRetries(t *testing.T) {
+	t.Parallel()
+
+	h := createParseProject(t)
+	defer h.Stop()
+
+	var calls int64
+	deploys := make(chan struct{}, 10)
+	deployer := deployFunc(func(parseVersion string,
+		prevDeplInfo *deployInfo,
+		forDevelop bool,
+		e *parsecli.Env) (*deployInfo, error) {
+		deploys <- struct{}{}
+		if atomic.AddInt64(&calls, 1) == 1 {
+			return nil, errors.New("network is down")
+		}
+		return &deployInfo{}, nil
+	})
+
+	done := make(chan struct{})
+	first := make(chan struct{})
+	d := &developCmd{deployInterval: time.Second}
+	go d.contDeploy(h.Env, deployer, first, done)
+	defer close(done)
+	<-first
+	<-deploys
+
+	// the failed deploy is retried without any change
+	ensure.True(t, waitDebounced(t, h.Clock, deploys) >= time.Second)
+
+	// and is not retried anymore once it succeeded
+	time.Sleep(10 * time.Millisecond)
+	h.Clock.Add(time.Minute)
+	select {
+	case <-deploys:
+		t.Fatal("unexpected deploy")
+	case <-time.After(50 * time.Millisecond):
+	}
+	ensure.DeepEqual(t, atomic.LoadInt64(&calls), int64(2))
+}
+
+func TestDevelopReport(t *testing.T) {
+	t.Parallel()
+	h := parsecli.NewHarness(t)
+	defer h.Stop()
+
+	var (
+		d      developCmd
+		status developStatus
+	)
+	// failures before any deploy succeeded do not alert
+	d.report(h.Env, &status, true, errors.New("first"))
+	d.report(h.Env, &status, true, nil)
+	d.report(h.Env, &status, true, errors.New("broken\nrelease"))
+	// a deploy without changes after the failure does not alert again
+	d.report(h.Env, &status, false, nil)
+	d.report(h.Env, &status, true, errors.New("still broken"))
+	ensure.DeepEqual(t, h.Err.String(), `Deploy failed:
+  first
+Deploy failed:
+  broken
+  release
+`+"\a"+`Deploy failed:
+  still broken
+`)
+}
+
+func TestDevelopReportRepeatedFailure(t *testing.T) {
+	t.Parallel()
+	h := parsecli.NewHarness(t)
+	defer h.Stop()
+
+	d := developCmd{}
+	status := developStatus{green: true}
+	// every failed cycle is printed, but only the first one alerts
+	d.report(h.Env, &status, true, errors.New("syntax error"))
+	d.report(h.Env, &status, true, errors.New("syntax error"))
+	ensure.DeepEqual(t, h.Err.String(), "Deploy failed:\n  syntax error\n\a"+
+		"Deploy failed:\n  syntax error\n")
+}
+
+func TestDevelopNotifyCommand(t *testing.T) {
+	t.Parallel()
+	if runtime.GOOS == "windows" {
+		t.Skip("notify command uses sh")
+	}
+	h := parsecli.NewHarness(t)
+	defer h.Stop()
+	h.MakeEmptyRoot()
+
+	d := developCmd{NotifyCommand: `echo "$PARSE_DEVELOP_ERROR" > notified`}
+	status := developStatus{green: true}
+	d.report(h.Env, &status, true, errors.New("release failed"))
+	content, err := ioutil.ReadFile(filepath.Join(h.Env.Root, "notified"))
+	ensure.Nil(t, err)
+	ensure.DeepEqual(t, string(content), "release failed\n")
+	ensure.DeepEqual(t, h.Err.String(), "Deploy failed:\n  release failed\n")
+}
+
+func TestPrintDevelopChanges(t *testing.T) {
+	t.Parallel()
+	h := parsecli.NewHarness(t)
+	defer h.Stop()
+
+	prev := &deployInfo{Checksums: deployFileData{
+		Cloud:  map[string]string{"main.js": "1", "lib.js": "1"},
+		Public: map[string]string{"index.html": "1"},
+	}}
+	printDevelopChanges(h.Env, prev, &deployFileData{
+		Cloud:  map[string]string{"main.js": "2", "lib.js": "1"},
+		Public: map[string]string{"app.js": "1"},
+	})
+	ensure.DeepEqual(t, h.Out.String(), `Changed files:
+  modified cloud/main.js
+  added    public/app.js
+  removed  public/index.html
+`)
+}
+
 func TestHandleError(t *testing.T) {
 	t.Parallel()
 	h := parsecli.NewHarness(t)
@@ -0,0 +1,167 @@
+package parsecmd
+
+import (
+	"fmt"
+	"io/ioutil"
+	"path"
+	"sort"
+
+	"github.com/ParsePlatform/parse-cli/parsecli"
+	"github.com/facebookgo/stackerr"
+	"github.com/spf13/cobra"
+)
+
+type diffCmd struct {
+	release string
+	sources sourceDirList
+}
+
+// changedFiles returns the files that may differ between the release and the
+// local checksums. Releases listed by "parse releases" carry no checksums, so
+// in that case every file present on both sides is a candidate.
+func (d *diffCmd) changedFiles(releaseChecksums, releaseVersions,
+	localChecksums map[string]string) *changeSet {
+	if len(releaseChecksums) != 0 {
+		return diffChecksums(releaseChecksums, localChecksums)
+	}
+
+	var c changeSet
+	for name := range localChecksums {
+		if _, ok := releaseVersions[name]; ok {
+			c.Modified = append(c.Modified, name)
+		} else {
+			c.Added = append(c.Added, name)
+		}
+	}
+	for name := range releaseVersions {
+		if _, ok := localChecksums[name]; !ok {
+			c.Removed = append(c.Removed, name)
+		}
+	}
+	sort.Strings(c.Added)
+	sort.Strings(c.Modified)
+	sort.Strings(c.Removed)
+	return &c
+}
+
+func (d *diffCmd) diffTarget(
+	target deployTarget,
+	release *deployInfo,
+	e *parsecli.Env,
+) (bool, error) {
+	deployer := deployCmd{sources: d.sources}
+	localFiles, err := deployer.targetFiles(target, e)
+	if err != nil {
+		return false, err
+	}
+	localChecksums, err := deployer.targetChecksums(target, e)
+	if err != nil {
+		return false, err
+	}
+	releaseChecksums, releaseVersions := target.checksums(release), target.versions(release)
+	changes := d.changedFiles(releaseChecksums, releaseVersions, localChecksums)
+
+	readRemote := func(name string) ([]byte, error) {
+		return target.fetch(e, name, releaseVersions[name], releaseChecksums[name])
+	}
+	readLocal := func(name string) ([]byte, error) {
+		content, err := ioutil.ReadFile(localFiles[name])
+		return content, stackerr.Wrap(err)
+	}
+
+	differ := false
+	printDiff := func(name string, remote, local bool) error {
+		var from, to []byte
+		fromName, toName := "/dev/null", "/dev/null"
+		if remote {
+			if _, ok := releaseVersions[name]; !ok {
+				return stackerr.Newf("No version of %s found in release %s", name, release.ReleaseName)
+			}
+			content, err := readRemote(name)
+			if err != nil {
+				return err
+			}
+			from, fromName = content, path.Join("a", target.Name, name)
+		}
+		if local {
+			content, err := readLocal(name)
+			if err != nil {
+				return err
+			}
+			to, toName = content, path.Join("b", target.Name, name)
+		}
+		if diff := unifiedDiff(fromName, toName, from, to); diff != "" {
+			differ = true
+			fmt.Fprint(e.Out, diff)
+		}
+		return nil
+	}
+
+	for _, name := range changes.Modified {
+		if err := printDiff(name, true, true); err != nil {
+			return false, err
+		}
+	}
+	for _, name := range changes.Added {
+		if err := printDiff(name, false, true); err != nil {
+			return false, err
+		}
+	}
+	for _, name := range changes.Removed {
+		if err := printDiff(name, true, false); err != nil {
+			return false, err
+		}
+	}
+	return differ, nil
+}
+
+func (d *diffCmd) run(e *parsecli.Env, c *parsecli.Context) error {
+	sources, err := projectSourceDirs(c.Config.GetProjectConfig())
+	if err != nil {
+		return err
+	}
+	d.sources = sources
+
+	var release *deployInfo
+	if d.release == "" {
+		release, err = (&deployCmd{}).getPrevDeplInfo(e)
+	} else {
+		release, err = releaseDeployInfo(e, d.release)
+	}
+	if err != nil {
+		return err
+	}
+
+	differ := false
+	for _, target := range deployTargets {
+		targetDiffers, err := d.diffTarget(target, release, e)
+		if err != nil {
+			return err
+		}
+		differ = differ || targetDiffers
+	}
+
+	if !differ {
+		releaseName := release.ReleaseName
+		if releaseName == "" {
+			releaseName = "the latest release"
+		}
+		fmt.Fprintf(e.Out, "No differences between local files and %s.\n", releaseName)
+	}
+	return nil
+}
+
+func NewDiffCmd(e *parsecli.Env) *cobra.Command {
+	d := &diffCmd{}
+	cmd := &cobra.Command{
+		Use:   "diff [app]",
+		Short: "Shows changes between local files and a deployed release",
+		Long: `Downloads the deployed versions of changed files and prints unified diffs
+against the local files. Compares with the latest release unless a release
+listed by "parse releases" is provided through the -r flag.`,
+		Run: parsecli.RunWithClient(e, d.run),
+	}
+	cmd.Flags().StringVarP(&d.release, "release", "r", d.release,
+		"Compare with the given release instead of the latest one.")
+	return cmd
+}
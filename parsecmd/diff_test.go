@@ -0,0 +1,122 @@
+package parsecmd
+
+import (
+	"io/ioutil"
+	"net/http"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/ParsePlatform/parse-cli/parsecli"
+	"github.com/facebookgo/ensure"
+	"github.com/facebookgo/parse"
+)
+
+func newDiffHarness(t testing.TB) *parsecli.Harness {
+	h := createParseProject(t)
+	info := &deployInfo{
+		ReleaseName: "v2",
+		Checksums: deployFileData{
+			Cloud: map[string]string{
+				"main.js": "d41d8cd98f00b204e9800998ecf8427e",
+				"old.js":  "d41d8cd98f00b204e9800998ecf8427e",
+			},
+			Public: map[string]string{"index.html": "9e2354a0ebac5852bc674026137c8612"},
+		},
+		Versions: deployFileData{
+			Cloud: map[string]string{
+				"main.js": "2",
+				"old.js":  "2",
+			},
+			Public: map[string]string{"index.html": "2"},
+		},
+	}
+	releases := []releasesResponse{
+		{
+			Version:   "v1",
+			UserFiles: `{"cloud": {"main.js": "1"}, "public": {"index.html": "1"}}`,
+		},
+	}
+
+	ht := parsecli.TransportFunc(func(r *http.Request) (*http.Response, error) {
+		var body string
+		switch r.URL.Path {
+		case "/1/deploy":
+			body = jsonStr(t, info)
+		case "/1/releases":
+			body = jsonStr(t, releases)
+		case "/1/scripts/main.js":
+			body = jsonStr(t, "echo {\"success\": \"ok\"}\nold line\n")
+		case "/1/scripts/old.js":
+			body = jsonStr(t, "removed\n")
+		case "/1/hosted_files/index.html":
+			body = jsonStr(t, []byte("<html>\n</html>\n"))
+		default:
+			return &http.Response{
+				StatusCode: http.StatusNotFound,
+				Body:       ioutil.NopCloser(strings.NewReader(`{"error": "something is wrong"}`)),
+			}, nil
+		}
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Body:       ioutil.NopCloser(strings.NewReader(body)),
+		}, nil
+	})
+	h.Env.ParseAPIClient = &parsecli.ParseAPIClient{APIClient: &parse.Client{Transport: ht}}
+	return h
+}
+
+func TestDiffLatestRelease(t *testing.T) {
+	t.Parallel()
+	h := newDiffHarness(t)
+	defer h.Stop()
+
+	var d diffCmd
+	ensure.Nil(t, d.run(h.Env, &parsecli.Context{Config: defaultParseConfig}))
+	ensure.DeepEqual(t, h.Out.String(), `--- a/cloud/main.js
++++ b/cloud/main.js
+@@ -1,2 +1,1 @@
+-echo {"success": "ok"}
+-old line
++echo {"success": "ok"}
+\ No newline at end of file
+--- a/cloud/old.js
++++ /dev/null
+@@ -1,1 +0,0 @@
+-removed
+`)
+}
+
+func TestDiffWithRelease(t *testing.T) {
+	t.Parallel()
+	h := newDiffHarness(t)
+	defer h.Stop()
+
+	d := diffCmd{release: "v1"}
+	ensure.Nil(t, d.run(h.Env, &parsecli.Context{Config: defaultParseConfig}))
+	ensure.DeepEqual(t, h.Out.String(), `--- a/cloud/main.js
++++ b/cloud/main.js
+@@ -1,2 +1,1 @@
+-echo {"success": "ok"}
+-old line
++echo {"success": "ok"}
+\ No newline at end of file
+--- a/public/index.html
++++ b/public/index.html
+@@ -1,2 +1,3 @@
+ <html>
+-</html>
++<head> <title> Parse Project </title></head>
++</html>
+\ No newline at end of file
+`)
+}
+
+func TestDiffUnknownRelease(t *testing.T) {
+	t.Parallel()
+	h := newDiffHarness(t)
+	defer h.Stop()
+
+	d := diffCmd{release: "v3"}
+	ensure.Err(t, d.run(h.Env, &parsecli.Context{Config: defaultParseConfig}), regexp.MustCompile("Unable to fetch files for release version: v3"))
+}
@@ -10,7 +10,8 @@ import (
 	"os"
 	"path"
 	"path/filepath"
-	"sync/atomic"
+	"sort"
+	"sync"
 
 	"github.com/ParsePlatform/parse-cli/parsecli"
 	"github.com/facebookgo/errgroup"
@@ -20,8 +21,20 @@ import (
 
 type downloadCmd struct {
 	release     *deployInfo
+	releaseName string
 	destination string
 	force       bool
+	restore     bool
+	archiveName string
+	sources     sourceDirList
+}
+
+// sourceDirs returns the directories the downloaded files are written to.
+func (d *downloadCmd) sourceDirs() sourceDirList {
+	if d.sources == nil {
+		return defaultSourceDirs
+	}
+	return d.sources
 }
 
 var errNoFiles = errors.New("Nothing to download. Not yet deployed to the app.")
@@ -47,131 +60,258 @@ func (d *downloadCmd) verifyChecksum(path, checksum string) error {
 	return nil
 }
 
+// backupFiles saves the project files that will be overwritten by the release.
+func (d *downloadCmd) backupFiles(
+	e *parsecli.Env,
+	backup *projectBackup,
+	release *deployInfo) error {
+	for _, target := range deployTargets {
+		for file := range target.versions(release) {
+			if err := backup.save(e.Root, d.sourceDirs().localPath("", target, file)); err != nil {
+				return err
+			}
+		}
+	}
+	return backup.commit()
+}
+
+// backupRelease backs up the project files that will be overwritten by the
+// release.
+func (d *downloadCmd) backupRelease(e *parsecli.Env, release *deployInfo) (*projectBackup, error) {
+	backup, err := newProjectBackup(e)
+	if err != nil {
+		return nil, err
+	}
+	if err := d.backupFiles(e, backup, release); err != nil {
+		backup.remove()
+		return nil, err
+	}
+	return backup, nil
+}
+
+// pruneBackups removes the backups older than the one of a successful
+// download. Failing to remove them does not fail the download.
+func (d *downloadCmd) pruneBackups(e *parsecli.Env, backup *projectBackup) {
+	if err := backup.removeOlder(e); err != nil {
+		fmt.Fprintf(e.Err, "Could not remove old backups:\n%s\n", parsecli.ErrorString(e, err))
+	}
+}
+
+// rollback restores the original project files after a failed download and
+// returns the download error.
+func (d *downloadCmd) rollback(e *parsecli.Env, backup *projectBackup, err error) error {
+	if rerr := backup.restore(e.Root); rerr != nil {
+		fmt.Fprintf(
+			e.Out,
+			`Failed to download Cloud Code to
+ %q
+
+Could not restore the original files, due to:
+%s
+
+Try "parse download --restore-backup" to restore the files
+backed up at %q.
+`,
+			e.Root,
+			parsecli.ErrorString(e, rerr),
+			backup.dir,
+		)
+		return err
+	}
+	backup.remove()
+
+	fmt.Fprintf(
+		e.Out,
+		`Failed to download Cloud Code to
+ %q
+The original files were restored.
+`,
+		e.Root,
+	)
+	return err
+}
+
 func (d *downloadCmd) moveFiles(
 	e *parsecli.Env,
 	destination string,
 	release *deployInfo) error {
+	backup, err := d.backupRelease(e, release)
+	if err != nil {
+		return err
+	}
+
 	var wg errgroup.Group
 
 	maxParallel := make(chan struct{}, maxOpenFD)
-	numFiles := len(release.Versions.Cloud) + len(release.Versions.Public)
-	wg.Add(numFiles)
+	wg.Add(len(release.Checksums.Cloud) + len(release.Checksums.Public))
 
-	var numErrors int32
-	moveFile := func(destination, kind, file, checksum string) {
+	moveFile := func(destination string, target deployTarget, file, checksum string) {
 		defer func() {
 			wg.Done()
 			<-maxParallel
 		}()
 
-		err := os.MkdirAll(
-			filepath.Join(e.Root, kind, filepath.Dir(file)),
-			0755,
-		)
+		dst := d.sourceDirs().localPath(e.Root, target, file)
+		err := os.MkdirAll(filepath.Dir(dst), 0755)
 		if err != nil {
-			atomic.AddInt32(&numErrors, 1)
 			wg.Error(stackerr.Wrap(err))
 			return
 		}
 
 		err = os.Rename(
-			filepath.Join(destination, kind, file),
-			filepath.Join(e.Root, kind, file),
+			d.sourceDirs().localPath(destination, target, file),
+			dst,
 		)
 		if err != nil {
-			atomic.AddInt32(&numErrors, 1)
 			wg.Error(stackerr.Wrap(err))
 			return
 		}
 
-		err = d.verifyChecksum(
-			filepath.Join(e.Root, kind, file),
-			checksum,
-		)
+		err = d.verifyChecksum(dst, checksum)
 		if err != nil {
-			atomic.AddInt32(&numErrors, 1)
 			wg.Error(err)
 			return
 		}
 	}
 
-	for file, checksum := range release.Checksums.Cloud {
-		maxParallel <- struct{}{}
-		go moveFile(
-			destination,
-			parsecli.CloudDir,
-			file,
-			checksum,
-		)
-	}
-	for file, checksum := range release.Checksums.Public {
-		maxParallel <- struct{}{}
-		go moveFile(
-			destination,
-			parsecli.HostingDir,
-			file,
-			checksum,
-		)
-	}
-
-	if err := wg.Wait(); err != nil {
-		// could not move a single file so no corruption:w
-		if int(numErrors) == numFiles {
-			fmt.Fprintf(
-				e.Out,
-				`Failed to download Cloud Code to
- %q
-Try "parse download" and manually move contents from
-the temporary download location.
-`,
-				e.Root,
+	for _, target := range deployTargets {
+		for file, checksum := range target.checksums(release) {
+			maxParallel <- struct{}{}
+			go moveFile(
+				destination,
+				target,
+				file,
+				checksum,
 			)
-			return nil
 		}
+	}
 
-		fmt.Fprintf(
-			e.Out,
-			`Failed to download Cloud Code to
- %q
-
-It might have corrupted contents, due to partially moved files.
+	if err := wg.Wait(); err != nil {
+		return d.rollback(e, backup, err)
+	}
+	d.pruneBackups(e, backup)
 
-Try "parse download" and manually move contents from
-the temporary download location.
+	fmt.Fprintf(
+		e.Out,
+		`Successfully downloaded Cloud Code to %q.
+Run "parse download --restore-backup" to restore the overwritten files.
 `,
-			e.Root,
-		)
+		e.Root,
+	)
+	return nil
+}
+
+// restoreBackup undoes the last forced download.
+func (d *downloadCmd) restoreBackup(e *parsecli.Env) error {
+	backup, err := latestProjectBackup(e)
+	if err != nil {
 		return err
 	}
+	if err := backup.restore(e.Root); err != nil {
+		return err
+	}
+	if err := backup.remove(); err != nil {
+		return err
+	}
+	fmt.Fprintf(
+		e.Out,
+		"Restored files overwritten by the last forced download in %q from backup %s.\n",
+		e.Root,
+		filepath.Base(backup.dir),
+	)
 	return nil
 }
 
+func fileURL(endpoint, file, version, checksum string) *url.URL {
+	v := make(url.Values)
+	v.Set("version", version)
+	if checksum != "" {
+		v.Set("checksum", checksum)
+	}
+	return &url.URL{
+		Path:     path.Join(endpoint, file),
+		RawQuery: v.Encode(),
+	}
+}
+
+// fetchHosted fetches the content of the given version of a hosted file.
+func fetchHosted(e *parsecli.Env, file, version, checksum string) ([]byte, error) {
+	var content []byte
+	if _, err := e.ParseAPIClient.Get(fileURL("hosted_files", file, version, checksum), &content); err != nil {
+		return nil, stackerr.Wrap(err)
+	}
+	return content, nil
+}
+
+// fetchScript fetches the content of the given version of a Cloud Code script.
+func fetchScript(e *parsecli.Env, file, version, checksum string) ([]byte, error) {
+	var content string
+	if _, err := e.ParseAPIClient.Get(fileURL("scripts", file, version, checksum), &content); err != nil {
+		return nil, stackerr.Wrap(err)
+	}
+	return []byte(content), nil
+}
+
+// maxDownloadAttempts bounds how many times a file whose content does not
+// match its checksum is fetched before giving up on it.
+const maxDownloadAttempts = 3
+
+func contentChecksum(content []byte) string {
+	return fmt.Sprintf("%x", md5.Sum(content))
+}
+
+// fetchVerified fetches a file, fetching it again while its content does not
+// match the checksum. It reports whether the content matched in the end.
+func fetchVerified(
+	e *parsecli.Env,
+	fetch func(*parsecli.Env, string, string, string) ([]byte, error),
+	file, version, checksum string,
+) ([]byte, bool, error) {
+	for attempt := 1; ; attempt++ {
+		content, err := fetch(e, file, version, checksum)
+		if err != nil {
+			return nil, false, err
+		}
+		if checksum == "" || contentChecksum(content) == checksum {
+			return content, true, nil
+		}
+		if attempt == maxDownloadAttempts {
+			return nil, false, nil
+		}
+	}
+}
+
 func (d *downloadCmd) download(e *parsecli.Env, destination string, release *deployInfo) error {
-	var wg errgroup.Group
+	var (
+		wg          errgroup.Group
+		failedMutex sync.Mutex
+		failed      []string
+	)
 	maxParallel := make(chan struct{}, maxOpenFD)
-	wg.Add(len(release.Versions.Cloud) + len(release.Versions.Public))
+	numFiles := len(release.Versions.Cloud) + len(release.Versions.Public)
+	wg.Add(numFiles)
+	// sizes are not known before the files are downloaded
+	transfer := e.Progress.Start("Downloading", numFiles, 0)
 
-	downloadHosted := func(file, version, checksum string) {
+	downloadFile := func(target deployTarget, file, version, checksum string) {
 		defer func() {
 			wg.Done()
 			<-maxParallel
 		}()
 
-		v := make(url.Values)
-		v.Set("version", version)
-		v.Set("checksum", checksum)
-		u := &url.URL{
-			Path:     path.Join("hosted_files", file),
-			RawQuery: v.Encode(),
-		}
-		var content []byte
-		_, err := e.ParseAPIClient.Get(u, &content)
+		content, ok, err := fetchVerified(e, target.fetch, file, version, checksum)
 		if err != nil {
 			wg.Error(err)
 			return
 		}
+		if !ok {
+			failedMutex.Lock()
+			failed = append(failed, path.Join(target.Name, file))
+			failedMutex.Unlock()
+			return
+		}
 
-		path := path.Join(destination, parsecli.HostingDir, file)
+		path := d.sourceDirs().localPath(destination, target, file)
 		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
 			wg.Error(stackerr.Wrap(err))
 			return
@@ -180,70 +320,190 @@ func (d *downloadCmd) download(e *parsecli.Env, destination string, release *dep
 			wg.Error(stackerr.Wrap(err))
 			return
 		}
-		if err := d.verifyChecksum(path, checksum); err != nil {
-			wg.Error(err)
-			return
+		transfer.Add(int64(len(content)))
+		transfer.FileDone()
+	}
+
+	// checksums are missing for releases rebuilt from the releases listing,
+	// such files are downloaded without verification
+	for _, target := range deployTargets {
+		checksums := target.checksums(release)
+		for file, version := range target.versions(release) {
+			maxParallel <- struct{}{}
+			go downloadFile(target, file, version, checksums[file])
 		}
 	}
 
-	downloadScript := func(file, version, checksum string) {
-		defer func() {
-			wg.Done()
-			<-maxParallel
-		}()
+	err := wg.Wait()
+	transfer.Finish()
+	if err != nil {
+		return err
+	}
+	return reportChecksumFailures(e, failed)
+}
 
-		v := make(url.Values)
-		v.Set("version", version)
-		v.Set("checksum", checksum)
-		u := &url.URL{
-			Path:     path.Join("scripts", file),
-			RawQuery: v.Encode(),
+// reportChecksumFailures lists the files that never matched their checksums.
+func reportChecksumFailures(e *parsecli.Env, failed []string) error {
+	if len(failed) == 0 {
+		return nil
+	}
+
+	sort.Strings(failed)
+	fmt.Fprintf(
+		e.Err,
+		"The following files did not match their checksums after %d attempts:\n",
+		maxDownloadAttempts,
+	)
+	for _, file := range failed {
+		fmt.Fprintf(e.Err, "  %s\n", file)
+	}
+	return stackerr.Newf("%d downloaded files failed checksum verification", len(failed))
+}
+
+// changedFiles returns the part of the release made of files that are missing
+// from the project or whose local content differs from the release.
+func (d *downloadCmd) changedFiles(e *parsecli.Env, release *deployInfo) (*deployInfo, error) {
+	changed := *release
+	changed.Versions = deployFileData{
+		Cloud:  make(map[string]string),
+		Public: make(map[string]string),
+	}
+	changed.Checksums = deployFileData{
+		Cloud:  make(map[string]string),
+		Public: make(map[string]string),
+	}
+
+	for _, target := range deployTargets {
+		versions, checksums := target.versions(release), target.checksums(release)
+		names := make(map[string]string)
+		var files []string
+		for file := range versions {
+			if checksums[file] == "" {
+				continue
+			}
+			name := d.sourceDirs().localPath(e.Root, target, file)
+			if _, err := os.Stat(name); err == nil {
+				names[name] = file
+				files = append(files, name)
+			}
 		}
-		var content string
-		_, err := e.ParseAPIClient.Get(u, &content)
+		local, err := (&deployCmd{}).computeChecksums(
+			files,
+			func(name string) string { return names[name] },
+		)
 		if err != nil {
-			wg.Error(err)
-			return
+			return nil, err
 		}
 
-		path := path.Join(destination, parsecli.CloudDir, file)
-		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
-			wg.Error(stackerr.Wrap(err))
-			return
-		}
-		if err := ioutil.WriteFile(path, []byte(content), 0644); err != nil {
-			wg.Error(stackerr.Wrap(err))
-			return
+		changedVersions, changedChecksums := target.versions(&changed), target.checksums(&changed)
+		for file, version := range versions {
+			checksum, ok := checksums[file]
+			if ok && local[file] == checksum {
+				continue
+			}
+			changedVersions[file] = version
+			if ok {
+				changedChecksums[file] = checksum
+			}
 		}
 	}
+	return &changed, nil
+}
 
-	for file, version := range release.Versions.Public {
-		checksum, ok := release.Checksums.Public[file]
-		if !ok {
-			continue
-		}
-		maxParallel <- struct{}{}
-		go downloadHosted(file, version, checksum)
+// downloadIntoProject fetches the files of the release that differ from the
+// local ones and writes them straight into the project.
+func (d *downloadCmd) downloadIntoProject(e *parsecli.Env, release *deployInfo) error {
+	changed, err := d.changedFiles(e, release)
+	if err != nil {
+		return err
+	}
+	numChanged := len(changed.Versions.Cloud) + len(changed.Versions.Public)
+	numFiles := len(release.Versions.Cloud) + len(release.Versions.Public)
+	if numChanged == 0 {
+		fmt.Fprintf(e.Out, "All %d files in %q are up to date.\n", numFiles, e.Root)
+		return nil
 	}
 
-	for file, version := range release.Versions.Cloud {
-		checksum, ok := release.Checksums.Cloud[file]
-		if !ok {
+	backup, err := d.backupRelease(e, changed)
+	if err != nil {
+		return err
+	}
+	if err := d.download(e, e.Root, changed); err != nil {
+		return d.rollback(e, backup, err)
+	}
+	d.pruneBackups(e, backup)
+
+	fmt.Fprintf(
+		e.Out,
+		`Successfully downloaded %d changed files to %q, skipped %d unchanged files.
+Run "parse download --restore-backup" to restore the overwritten files.
+`,
+		numChanged,
+		e.Root,
+		numFiles-numChanged,
+	)
+	return nil
+}
+
+// fillChecksums computes the checksums of downloaded files for which the
+// release has no recorded checksum.
+func (d *downloadCmd) fillChecksums(destination string, release *deployInfo) error {
+	if release.Checksums.Cloud == nil {
+		release.Checksums.Cloud = make(map[string]string)
+	}
+	if release.Checksums.Public == nil {
+		release.Checksums.Public = make(map[string]string)
+	}
+
+	for _, target := range deployTargets {
+		checksums := target.checksums(release)
+		names := make(map[string]string)
+		var files []string
+		for file := range target.versions(release) {
+			if _, ok := checksums[file]; !ok {
+				name := d.sourceDirs().localPath(destination, target, file)
+				names[name] = file
+				files = append(files, name)
+			}
+		}
+		if len(files) == 0 {
 			continue
 		}
-		maxParallel <- struct{}{}
-		go downloadScript(file, version, checksum)
+		computed, err := (&deployCmd{}).computeChecksums(
+			files,
+			func(name string) string { return names[name] },
+		)
+		if err != nil {
+			return err
+		}
+		for file, checksum := range computed {
+			checksums[file] = checksum
+		}
 	}
-
-	return wg.Wait()
+	return nil
 }
 
 func (d *downloadCmd) run(e *parsecli.Env, c *parsecli.Context) error {
+	if d.restore {
+		return d.restoreBackup(e)
+	}
+
 	var err error
+	// the context is missing when downloading into a project being created
+	if c != nil {
+		d.sources, err = projectSourceDirs(c.Config.GetProjectConfig())
+		if err != nil {
+			return err
+		}
+	}
 
 	latestRelease := d.release
 	if latestRelease == nil {
-		latestRelease, err = (&deployCmd{}).getPrevDeplInfo(e)
+		if d.releaseName != "" {
+			latestRelease, err = releaseDeployInfo(e, d.releaseName)
+		} else {
+			latestRelease, err = (&deployCmd{}).getPrevDeplInfo(e)
+		}
 		if err != nil {
 			return err
 		}
@@ -252,6 +512,15 @@ func (d *downloadCmd) run(e *parsecli.Env, c *parsecli.Context) error {
 		}
 	}
 
+	if d.archiveName != "" {
+		return d.archive(e, d.archiveName, latestRelease)
+	}
+
+	// files are only compared with the project when writing into it
+	if d.force && d.destination == "" {
+		return d.downloadIntoProject(e, latestRelease)
+	}
+
 	destination := d.destination
 	if destination == "" {
 		destination, err = ioutil.TempDir("", "parse_code_")
@@ -265,6 +534,9 @@ func (d *downloadCmd) run(e *parsecli.Env, c *parsecli.Context) error {
 		fmt.Fprintln(e.Err, "Failed to download Cloud Code.")
 		return stackerr.Wrap(err)
 	}
+	if err := d.fillChecksums(destination, latestRelease); err != nil {
+		return err
+	}
 	if !d.force {
 		fmt.Fprintf(e.Out, "Successfully downloaded Cloud Code to %q.\n", destination)
 		return nil
@@ -280,12 +552,24 @@ func NewDownloadCmd(e *parsecli.Env) *cobra.Command {
 		Short: "Downloads the Cloud Code project",
 		Long: `Downloads the Cloud Code project at a given location,
 or at a temporary location if nothing is explicitly provided through the -l flag.
+
+With -f and no location, only the files that differ from the local ones are
+downloaded, straight into the current project directory.
+
+With --archive, the release and a manifest of its checksums are written
+into a single .tar.gz or .zip archive, and no other file is written.
 `,
 		Run: parsecli.RunWithClient(e, d.run),
 	}
 	cmd.Flags().BoolVarP(&d.force, "force", "f", d.force,
 		"Force will overwrite any files in the current project directory")
+	cmd.Flags().BoolVar(&d.restore, "restore-backup", d.restore,
+		"Restore the files overwritten by the last forced download")
 	cmd.Flags().StringVarP(&d.destination, "location", "l", d.destination,
 		"Download Cloud Code project at the given location.")
+	cmd.Flags().StringVar(&d.archiveName, "archive", d.archiveName,
+		"Write the release with a manifest into the given .tar.gz or .zip archive instead.")
+	cmd.Flags().StringVarP(&d.releaseName, "release", "r", d.releaseName,
+		`Download the given release shown in "parse releases" instead of the latest one.`)
 	return cmd
 }
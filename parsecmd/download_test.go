@@ -1,12 +1,14 @@
 package parsecmd
 
 import (
+	"fmt"
 	"io/ioutil"
 	"net/http"
 	"os"
 	"path/filepath"
 	"regexp"
 	"strings"
+	"sync"
 	"testing"
 
 	"github.com/ParsePlatform/parse-cli/parsecli"
@@ -85,6 +87,73 @@ func TestDownload(t *testing.T) {
 	}
 }
 
+// newCorruptingTransport serves scripts whose content is corrupted for the
+// first corrupt requests of each file.
+func newCorruptingTransport(corrupt int) http.RoundTripper {
+	var mutex sync.Mutex
+	requests := make(map[string]int)
+	return parsecli.TransportFunc(func(r *http.Request) (*http.Response, error) {
+		mutex.Lock()
+		requests[r.URL.Path]++
+		n := requests[r.URL.Path]
+		mutex.Unlock()
+
+		body := `"content"`
+		if n <= corrupt {
+			body = `"c0ntent"`
+		}
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Body:       ioutil.NopCloser(strings.NewReader(body)),
+		}, nil
+	})
+}
+
+func TestDownloadRetriesChecksumMismatch(t *testing.T) {
+	t.Parallel()
+	h, d := newDownloadHarness(t)
+	defer h.Stop()
+	h.Env.ParseAPIClient = &parsecli.ParseAPIClient{
+		APIClient: &parse.Client{Transport: newCorruptingTransport(maxDownloadAttempts - 1)},
+	}
+	d.release.Versions.Public = nil
+
+	tempDir, err := ioutil.TempDir("", "download_")
+	ensure.Nil(t, err)
+	defer os.RemoveAll(tempDir)
+
+	ensure.Nil(t, d.download(h.Env, tempDir, d.release))
+	content, err := ioutil.ReadFile(filepath.Join(tempDir, parsecli.CloudDir, "main.js"))
+	ensure.Nil(t, err)
+	ensure.DeepEqual(t, string(content), "content")
+}
+
+func TestDownloadChecksumFailureReport(t *testing.T) {
+	t.Parallel()
+	h, d := newDownloadHarness(t)
+	defer h.Stop()
+	h.Env.ParseAPIClient = &parsecli.ParseAPIClient{
+		APIClient: &parse.Client{Transport: newCorruptingTransport(maxDownloadAttempts)},
+	}
+	d.release.Versions.Cloud["util/helper.js"] = "version"
+	d.release.Checksums.Cloud["util/helper.js"] = "9a0364b9e99bb480dd25e1f0284c8555"
+	d.release.Versions.Public = nil
+
+	tempDir, err := ioutil.TempDir("", "download_")
+	ensure.Nil(t, err)
+	defer os.RemoveAll(tempDir)
+
+	ensure.Err(t, d.download(h.Env, tempDir, d.release),
+		regexp.MustCompile("2 downloaded files failed checksum verification"))
+	ensure.DeepEqual(t, h.Err.String(),
+		`The following files did not match their checksums after 3 attempts:
+  cloud/main.js
+  cloud/util/helper.js
+`)
+	_, err = os.Stat(filepath.Join(tempDir, parsecli.CloudDir, "main.js"))
+	ensure.True(t, os.IsNotExist(err))
+}
+
 func TestDownloadMoveFiles(t *testing.T) {
 	t.Parallel()
 	h, d := newDownloadHarness(t)
@@ -135,3 +204,233 @@ func TestDownloadMoveFiles(t *testing.T) {
 	)
 	ensure.DeepEqual(t, readData, content)
 }
+
+func TestDownloadRelease(t *testing.T) {
+	t.Parallel()
+	h := parsecli.NewHarness(t)
+	h.MakeEmptyRoot()
+	defer h.Stop()
+
+	releases := []releasesResponse{
+		{Version: "v1", UserFiles: `{"cloud": {"main.js": "1"}, "public": {"index.html": "1"}}`},
+		{Version: "v2", UserFiles: `{"cloud": {"main.js": "2"}}`},
+	}
+	ht := parsecli.TransportFunc(func(r *http.Request) (*http.Response, error) {
+		var body string
+		switch {
+		case r.URL.Path == "/1/releases":
+			body = jsonStr(t, releases)
+		case scriptPath.MatchString(r.URL.Path):
+			ensure.DeepEqual(t, r.FormValue("version"), "1")
+			body = `"content"`
+		case hostedPath.MatchString(r.URL.Path):
+			ensure.DeepEqual(t, r.FormValue("version"), "1")
+			body = jsonStr(t, []byte("content"))
+		default:
+			return &http.Response{
+				StatusCode: http.StatusNotFound,
+				Body:       ioutil.NopCloser(strings.NewReader(`{"error": "something is wrong"}`)),
+			}, nil
+		}
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Body:       ioutil.NopCloser(strings.NewReader(body)),
+		}, nil
+	})
+	h.Env.ParseAPIClient = &parsecli.ParseAPIClient{APIClient: &parse.Client{Transport: ht}}
+
+	tempDir, err := ioutil.TempDir("", "download_release_")
+	ensure.Nil(t, err)
+	defer os.RemoveAll(tempDir)
+
+	d := &downloadCmd{releaseName: "v1", destination: tempDir}
+	ensure.Nil(t, d.run(h.Env, &parsecli.Context{Config: defaultParseConfig}))
+
+	for _, file := range []string{
+		filepath.Join(tempDir, parsecli.CloudDir, "main.js"),
+		filepath.Join(tempDir, parsecli.HostingDir, "index.html"),
+	} {
+		content, err := ioutil.ReadFile(file)
+		ensure.Nil(t, err)
+		ensure.DeepEqual(t, string(content), "content")
+	}
+	ensure.StringContains(t, h.Out.String(), "Successfully downloaded Cloud Code")
+}
+
+func TestDownloadFillChecksums(t *testing.T) {
+	t.Parallel()
+	h, d := newDownloadHarness(t)
+	defer h.Stop()
+
+	tempDir, err := ioutil.TempDir("", "fill_checksums_")
+	ensure.Nil(t, err)
+	defer os.RemoveAll(tempDir)
+
+	release := &deployInfo{
+		Versions: deployFileData{
+			Cloud: map[string]string{"main.js": "version"},
+		},
+	}
+	ensure.Nil(t, d.download(h.Env, tempDir, release))
+	ensure.Nil(t, d.fillChecksums(tempDir, release))
+	ensure.DeepEqual(t, release.Checksums.Cloud,
+		map[string]string{"main.js": "9a0364b9e99bb480dd25e1f0284c8555"})
+}
+
+func writeDownloadedFiles(t testing.TB, dir string, content []byte) {
+	for _, name := range []string{
+		filepath.Join(parsecli.CloudDir, "main.js"),
+		filepath.Join(parsecli.HostingDir, "index.html"),
+	} {
+		ensure.Nil(t, os.MkdirAll(filepath.Join(dir, filepath.Dir(name)), 0755))
+		ensure.Nil(t, ioutil.WriteFile(filepath.Join(dir, name), content, 0644))
+	}
+}
+
+func TestDownloadMoveFilesRestoresOnError(t *testing.T) {
+	t.Parallel()
+	h, d := newDownloadHarness(t)
+	defer h.Stop()
+
+	original := []byte("original")
+	writeDownloadedFiles(t, h.Env.Root, original)
+
+	tempDir, err := ioutil.TempDir("", "move_files_")
+	ensure.Nil(t, err)
+	defer os.RemoveAll(tempDir)
+	writeDownloadedFiles(t, tempDir, []byte("content"))
+	ensure.Nil(t, ioutil.WriteFile(filepath.Join(tempDir, parsecli.CloudDir, "new.js"), nil, 0644))
+
+	d.release.Checksums.Cloud["main.js"] = "9a0364b9e99bb480dd25e1f0284c8555"
+	d.release.Versions.Cloud["new.js"] = "version"
+	d.release.Checksums.Cloud["new.js"] = "d41d8cd98f00b204e9800998ecf8427e"
+	d.release.Checksums.Public["index.html"] = "bad checksum"
+
+	ensure.NotNil(t, d.moveFiles(h.Env, tempDir, d.release))
+	ensure.StringContains(t, h.Out.String(), "The original files were restored.")
+
+	for _, name := range []string{
+		filepath.Join(parsecli.CloudDir, "main.js"),
+		filepath.Join(parsecli.HostingDir, "index.html"),
+	} {
+		readData, err := ioutil.ReadFile(filepath.Join(h.Env.Root, name))
+		ensure.Nil(t, err)
+		ensure.DeepEqual(t, readData, original)
+	}
+	_, err = os.Stat(filepath.Join(h.Env.Root, parsecli.CloudDir, "new.js"))
+	ensure.True(t, os.IsNotExist(err))
+
+	_, err = latestProjectBackup(h.Env)
+	ensure.DeepEqual(t, err, errNoBackup)
+}
+
+func TestDownloadRestoreBackup(t *testing.T) {
+	t.Parallel()
+	h, d := newDownloadHarness(t)
+	defer h.Stop()
+
+	original := []byte("original")
+	writeDownloadedFiles(t, h.Env.Root, original)
+
+	tempDir, err := ioutil.TempDir("", "move_files_")
+	ensure.Nil(t, err)
+	defer os.RemoveAll(tempDir)
+	content := []byte("content")
+	writeDownloadedFiles(t, tempDir, content)
+	ensure.Nil(t, ioutil.WriteFile(filepath.Join(tempDir, parsecli.CloudDir, "new.js"), nil, 0644))
+
+	d.release.Checksums.Cloud["main.js"] = "9a0364b9e99bb480dd25e1f0284c8555"
+	d.release.Versions.Cloud["new.js"] = "version"
+	d.release.Checksums.Cloud["new.js"] = "d41d8cd98f00b204e9800998ecf8427e"
+	d.release.Checksums.Public["index.html"] = "9a0364b9e99bb480dd25e1f0284c8555"
+
+	ensure.Nil(t, d.moveFiles(h.Env, tempDir, d.release))
+	ensure.StringContains(t, h.Out.String(), "parse download --restore-backup")
+	readData, err := ioutil.ReadFile(filepath.Join(h.Env.Root, parsecli.CloudDir, "main.js"))
+	ensure.Nil(t, err)
+	ensure.DeepEqual(t, readData, content)
+
+	d.restore = true
+	ensure.Nil(t, d.run(h.Env, &parsecli.Context{Config: defaultParseConfig}))
+
+	for _, name := range []string{
+		filepath.Join(parsecli.CloudDir, "main.js"),
+		filepath.Join(parsecli.HostingDir, "index.html"),
+	} {
+		readData, err := ioutil.ReadFile(filepath.Join(h.Env.Root, name))
+		ensure.Nil(t, err)
+		ensure.DeepEqual(t, readData, original)
+	}
+	_, err = os.Stat(filepath.Join(h.Env.Root, parsecli.CloudDir, "new.js"))
+	ensure.True(t, os.IsNotExist(err))
+
+	ensure.Err(t, d.run(h.Env, &parsecli.Context{Config: defaultParseConfig}), regexp.MustCompile("No backup found to restore."))
+}
+
+func TestProjectBackupsOrderedAndPruned(t *testing.T) {
+	t.Parallel()
+	h, _ := newDownloadHarness(t)
+	defer h.Stop()
+
+	// backups taken in the same second are ordered by their sequence number
+	var backups []*projectBackup
+	for i := 0; i < 3; i++ {
+		b, err := newProjectBackup(h.Env)
+		ensure.Nil(t, err)
+		b.Created = []string{fmt.Sprintf("cloud/%d.js", i)}
+		ensure.Nil(t, b.commit())
+		backups = append(backups, b)
+	}
+	latest, err := latestProjectBackup(h.Env)
+	ensure.Nil(t, err)
+	ensure.DeepEqual(t, latest.Created, []string{"cloud/2.js"})
+
+	ensure.Nil(t, backups[2].removeOlder(h.Env))
+	names, err := backupDirs(h.Env)
+	ensure.Nil(t, err)
+	ensure.DeepEqual(t, names, []string{
+		"000003-" + h.Clock.Now().UTC().Format(backupTimeFormat),
+	})
+}
+
+func TestDownloadIncremental(t *testing.T) {
+	t.Parallel()
+	h, d := newDownloadHarness(t)
+	defer h.Stop()
+
+	var (
+		mutex     sync.Mutex
+		requested []string
+	)
+	ht := parsecli.TransportFunc(func(r *http.Request) (*http.Response, error) {
+		mutex.Lock()
+		requested = append(requested, r.URL.Path)
+		mutex.Unlock()
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Body:       ioutil.NopCloser(strings.NewReader(`[1, 1, 2, 3, 5, 8, 13]`)),
+		}, nil
+	})
+	h.Env.ParseAPIClient = &parsecli.ParseAPIClient{APIClient: &parse.Client{Transport: ht}}
+
+	// main.js is up to date, index.html is stale
+	writeDownloadedFiles(t, h.Env.Root, []byte("content"))
+	d.force = true
+
+	ensure.Nil(t, d.run(h.Env, &parsecli.Context{Config: defaultParseConfig}))
+	ensure.DeepEqual(t, requested, []string{"/1/hosted_files/index.html"})
+	ensure.DeepEqual(t, h.Out.String(),
+		fmt.Sprintf(`Successfully downloaded 1 changed files to %q, skipped 1 unchanged files.
+Run "parse download --restore-backup" to restore the overwritten files.
+`, h.Env.Root))
+
+	content, err := ioutil.ReadFile(filepath.Join(h.Env.Root, parsecli.HostingDir, "index.html"))
+	ensure.Nil(t, err)
+	ensure.DeepEqual(t, content, []byte{1, 1, 2, 3, 5, 8, 13})
+
+	h.Out.Reset()
+	requested = nil
+	ensure.Nil(t, d.run(h.Env, &parsecli.Context{Config: defaultParseConfig}))
+	ensure.DeepEqual(t, len(requested), 0)
+	ensure.DeepEqual(t, h.Out.String(), fmt.Sprintf("All 2 files in %q are up to date.\n", h.Env.Root))
+}
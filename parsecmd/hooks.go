@@ -0,0 +1,79 @@
+package parsecmd
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+
+	"github.com/ParsePlatform/parse-cli/parsecli"
+	"github.com/facebookgo/stackerr"
+)
+
+const (
+	predeployHook  = "predeploy"
+	postdeployHook = "postdeploy"
+)
+
+// deployHooks runs the commands configured in the hooks section of the
+// project config around deploys.
+type deployHooks struct {
+	Config  *parsecli.HooksConfig
+	AppName string
+}
+
+// newDeployHooks returns the hooks configured for the project in the context.
+func newDeployHooks(c *parsecli.Context) *deployHooks {
+	h := &deployHooks{AppName: c.AppName}
+	if h.AppName == parsecli.DefaultKey {
+		h.AppName = c.Config.GetDefaultApp()
+	}
+	if p := c.Config.GetProjectConfig(); p != nil && p.Parse != nil {
+		h.Config = p.Parse.Hooks
+	}
+	return h
+}
+
+func (h *deployHooks) commands(hook string) []string {
+	if h == nil || h.Config == nil {
+		return nil
+	}
+	switch hook {
+	case predeployHook:
+		return h.Config.Predeploy
+	case postdeployHook:
+		return h.Config.Postdeploy
+	}
+	return nil
+}
+
+func shellCommand(command string) *exec.Cmd {
+	if runtime.GOOS == "windows" {
+		return exec.Command("cmd", "/C", command)
+	}
+	return exec.Command("sh", "-c", command)
+}
+
+// run runs the commands of the hook in the project root, and stops at the
+// first failing command. The app name, the release name and the project root
+// are passed in the environment.
+func (h *deployHooks) run(e *parsecli.Env, hook, releaseName string) error {
+	for _, command := range h.commands(hook) {
+		fmt.Fprintf(e.Out, "Running %s hook: %s\n", hook, command)
+		cmd := shellCommand(command)
+		cmd.Dir = e.Root
+		cmd.Stdout = e.Out
+		cmd.Stderr = e.Err
+		cmd.Env = append(
+			os.Environ(),
+			"PARSE_HOOK="+hook,
+			"PARSE_APP_NAME="+h.AppName,
+			"PARSE_RELEASE_NAME="+releaseName,
+			"PARSE_ROOT="+e.Root,
+		)
+		if err := cmd.Run(); err != nil {
+			return stackerr.Newf("The %s hook %q failed: %s", hook, command, err)
+		}
+	}
+	return nil
+}
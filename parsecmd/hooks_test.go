@@ -0,0 +1,110 @@
+package parsecmd
+
+import (
+	"io/ioutil"
+	"net/http"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/ParsePlatform/parse-cli/parsecli"
+	"github.com/facebookgo/ensure"
+)
+
+func newHooksHarness(t *testing.T, hooks *parsecli.HooksConfig) (*parsecli.Harness, *deployCmd, *int32) {
+	if runtime.GOOS == "windows" {
+		t.Skip("hook commands in tests are written for sh")
+	}
+	h := setupForDeploy(t, &deployInfo{ReleaseName: "v2"})
+
+	var uploads int32
+	ht := h.Env.ParseAPIClient.APIClient.Transport
+	h.Env.ParseAPIClient.APIClient.Transport = parsecli.TransportFunc(
+		func(r *http.Request) (*http.Response, error) {
+			if r.URL.Path == "/1/scripts" || r.URL.Path == "/1/hosted_files" {
+				atomic.AddInt32(&uploads, 1)
+			}
+			return ht.RoundTrip(r)
+		})
+
+	d := &deployCmd{hooks: &deployHooks{Config: hooks, AppName: "app"}}
+	return h, d, &uploads
+}
+
+func TestPredeployFailureAbortsDeploy(t *testing.T) {
+	t.Parallel()
+	h, d, uploads := newHooksHarness(t, &parsecli.HooksConfig{
+		Predeploy:  []string{"true", "exit 3", "touch never"},
+		Postdeploy: []string{"touch posted"},
+	})
+	defer h.Stop()
+
+	_, err := d.deploy("latest", nil, false, h.Env)
+	ensure.Err(t, err, regexp.MustCompile(`The predeploy hook "exit 3" failed: exit status 3`))
+	ensure.DeepEqual(t, atomic.LoadInt32(uploads), int32(0))
+	ensure.StringContains(t, h.Out.String(), "Running predeploy hook: exit 3")
+	ensure.StringDoesNotContain(t, h.Out.String(), "touch never")
+	_, err = ioutil.ReadFile(filepath.Join(h.Env.Root, "posted"))
+	ensure.NotNil(t, err)
+}
+
+func TestPredeploySkippedForArchives(t *testing.T) {
+	t.Parallel()
+	h, d, _ := newHooksHarness(t, &parsecli.HooksConfig{
+		Predeploy: []string{"exit 3"},
+	})
+	defer h.Stop()
+
+	d.source = &releaseSource{Dir: h.Env.Root}
+	ensure.Nil(t, d.predeploy(false, h.Env))
+	ensure.StringDoesNotContain(t, h.Out.String(), "Running predeploy hook")
+}
+
+func TestDeployHooksEnvironment(t *testing.T) {
+	t.Parallel()
+	h, d, uploads := newHooksHarness(t, &parsecli.HooksConfig{
+		Predeploy:  []string{`echo "$PARSE_HOOK $PARSE_APP_NAME $PARSE_ROOT" > pre.out`},
+		Postdeploy: []string{`echo "$PARSE_HOOK $PARSE_APP_NAME $PARSE_RELEASE_NAME" > post.out`},
+	})
+	defer h.Stop()
+
+	_, err := d.deploy("latest", nil, false, h.Env)
+	ensure.Nil(t, err)
+	ensure.True(t, atomic.LoadInt32(uploads) > 0)
+
+	pre, err := ioutil.ReadFile(filepath.Join(h.Env.Root, "pre.out"))
+	ensure.Nil(t, err)
+	ensure.DeepEqual(t, strings.TrimSpace(string(pre)), "predeploy app "+h.Env.Root)
+	post, err := ioutil.ReadFile(filepath.Join(h.Env.Root, "post.out"))
+	ensure.Nil(t, err)
+	ensure.DeepEqual(t, strings.TrimSpace(string(post)), "postdeploy app v2")
+}
+
+func TestPredeployDevelopOnlyOnChanges(t *testing.T) {
+	t.Parallel()
+	h, d, _ := newHooksHarness(t, &parsecli.HooksConfig{
+		Predeploy: []string{"echo run >> runs.out"},
+	})
+	defer h.Stop()
+
+	runs := func() int {
+		content, err := ioutil.ReadFile(filepath.Join(h.Env.Root, "runs.out"))
+		ensure.Nil(t, err)
+		return strings.Count(string(content), "run")
+	}
+
+	ensure.Nil(t, d.predeploy(true, h.Env))
+	ensure.Nil(t, d.predeploy(true, h.Env))
+	ensure.DeepEqual(t, runs(), 1)
+
+	ensure.Nil(t, ioutil.WriteFile(
+		filepath.Join(h.Env.Root, parsecli.CloudDir, "main.js"),
+		[]byte("changed"),
+		0600,
+	))
+	ensure.Nil(t, d.predeploy(true, h.Env))
+	ensure.DeepEqual(t, runs(), 2)
+}
@@ -3,6 +3,8 @@ package parsecmd
 import (
 	"encoding/json"
 	"fmt"
+	"hash/fnv"
+	"io"
 	"net/url"
 	"strings"
 	"time"
@@ -12,7 +14,19 @@ import (
 	"github.com/spf13/cobra"
 )
 
-const logFollowSleepDuration = time.Second
+const (
+	logFollowSleepDuration = time.Second
+	// logOverlap is how far before the newest message a follow round asks for
+	// messages, so that messages logged with the same or a slightly older
+	// timestamp after the previous round are not missed. Messages printed
+	// within the overlap are remembered so they are not printed twice.
+	logOverlap = 5 * time.Second
+	// logMaxNum bounds how many messages a round fetches when full pages show
+	// that messages are missing.
+	logMaxNum = 1000
+	// parseISOFormat is the format of the timestamps of the Parse API.
+	parseISOFormat = "2006-01-02T15:04:05.000Z"
+)
 
 type parseTime struct {
 	Type string `json:"__type"`
@@ -24,10 +38,101 @@ type logResponse struct {
 	Message   string    `json:"message"`
 }
 
+// logKey identifies the messages logged at the same time with the same
+// content.
+type logKey struct {
+	ISO  string
+	Hash uint64
+}
+
+func newLogKey(row *logResponse) logKey {
+	h := fnv.New64a()
+	io.WriteString(h, row.Message)
+	return logKey{ISO: row.Timestamp.ISO, Hash: h.Sum64()}
+}
+
+// logWindow remembers the messages printed by follow mode which a later round
+// may return again.
+type logWindow struct {
+	// seen counts the printed messages of each key, so that a message logged
+	// twice at the same time is printed twice.
+	seen   map[logKey]int
+	newest *parseTime
+}
+
+// print prints the rows which were not printed yet.
+func (w *logWindow) print(out io.Writer, rows []logResponse) {
+	if w.seen == nil {
+		w.seen = make(map[logKey]int)
+	}
+	counts := make(map[logKey]int)
+	// logs come back in reverse
+	for i := len(rows) - 1; i >= 0; i-- {
+		key := newLogKey(&rows[i])
+		counts[key]++
+		if counts[key] > w.seen[key] {
+			fmt.Fprintln(out, rows[i].Message)
+			w.seen[key] = counts[key]
+		}
+		if w.newest == nil || rows[i].Timestamp.ISO > w.newest.ISO {
+			newest := rows[i].Timestamp
+			w.newest = &newest
+		}
+	}
+}
+
+// missed reports whether messages may have been logged between the messages
+// already seen and the rows of a full page. The page reaches back to them if
+// its oldest row is older than the newest message seen, or as old and the page
+// holds all the messages seen at that time, as messages logged at the same
+// time are not ordered.
+func (w *logWindow) missed(rows []logResponse) bool {
+	if w.newest == nil || len(rows) == 0 {
+		return false
+	}
+	oldest := rows[len(rows)-1].Timestamp.ISO
+	if oldest != w.newest.ISO {
+		return oldest > w.newest.ISO
+	}
+	counts := make(map[logKey]int)
+	for i := range rows {
+		if key := newLogKey(&rows[i]); key.ISO == oldest {
+			counts[key]++
+		}
+	}
+	for key, n := range w.seen {
+		if key.ISO == oldest && counts[key] < n {
+			return true
+		}
+	}
+	return false
+}
+
+// startTime returns the start time of the next round, and forgets the
+// messages older than it, which cannot be returned again. It is nil until a
+// message was seen.
+func (w *logWindow) startTime() *parseTime {
+	if w.newest == nil {
+		return nil
+	}
+	start := *w.newest
+	// timestamps are compared as strings if they cannot be parsed
+	if t, err := time.Parse(parseISOFormat, start.ISO); err == nil {
+		start.ISO = t.Add(-logOverlap).Format(parseISOFormat)
+	}
+	for key := range w.seen {
+		if key.ISO < start.ISO {
+			delete(w.seen, key)
+		}
+	}
+	return &start
+}
+
 type logsCmd struct {
 	num    uint
 	follow bool
 	level  string
+	window logWindow
 }
 
 func (l *logsCmd) run(e *parsecli.Env, c *parsecli.Context) error {
@@ -42,8 +147,7 @@ func (l *logsCmd) run(e *parsecli.Env, c *parsecli.Context) error {
 		l.num = 10
 	}
 
-	lastTime, err := l.round(e, c, nil)
-	if err != nil {
+	if err := l.round(e, c, nil); err != nil {
 		return err
 	}
 
@@ -57,17 +161,44 @@ func (l *logsCmd) run(e *parsecli.Env, c *parsecli.Context) error {
 	ticker := e.Clock.Ticker(logFollowSleepDuration)
 	defer ticker.Stop()
 	for range ticker.C {
-		lastTime, err = l.round(e, c, lastTime)
-		if err != nil {
+		if err := l.round(e, c, l.window.startTime()); err != nil {
 			return err
 		}
 	}
 	return nil
 }
 
-func (l *logsCmd) round(e *parsecli.Env, c *parsecli.Context, startTime *parseTime) (*parseTime, error) {
+// round prints the messages logged since startTime which were not printed
+// yet. A full page may not reach back to the messages already printed, the
+// messages are then fetched again with a larger page.
+func (l *logsCmd) round(e *parsecli.Env, c *parsecli.Context, startTime *parseTime) error {
+	num := l.num
+	for {
+		rows, err := l.fetch(e, num, startTime)
+		if err != nil {
+			return err
+		}
+		missed := uint(len(rows)) >= num && l.window.missed(rows)
+		if !missed || num >= logMaxNum {
+			if missed {
+				fmt.Fprintf(e.Err,
+					"More than %d messages were logged since the last round, some of them are not shown.\n",
+					num,
+				)
+			}
+			l.window.print(e.Out, rows)
+			return nil
+		}
+		num *= 2
+		if num > logMaxNum {
+			num = logMaxNum
+		}
+	}
+}
+
+func (l *logsCmd) fetch(e *parsecli.Env, num uint, startTime *parseTime) ([]logResponse, error) {
 	v := make(url.Values)
-	v.Set("n", fmt.Sprint(l.num))
+	v.Set("n", fmt.Sprint(num))
 	v.Set("level", l.level)
 
 	if startTime != nil {
@@ -86,14 +217,7 @@ func (l *logsCmd) round(e *parsecli.Env, c *parsecli.Context, startTime *parseTi
 	if _, err := e.ParseAPIClient.Get(u, &rows); err != nil {
 		return nil, stackerr.Wrap(err)
 	}
-	// logs come back in reverse
-	for i := len(rows) - 1; i >= 0; i-- {
-		fmt.Fprintln(e.Out, rows[i].Message)
-	}
-	if len(rows) > 0 {
-		return &rows[0].Timestamp, nil
-	}
-	return startTime, nil
+	return rows, nil
 }
 
 func NewLogsCmd(e *parsecli.Env) *cobra.Command {
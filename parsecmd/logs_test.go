@@ -1,12 +1,15 @@
 package parsecmd
 
 import (
+	"bytes"
+	"fmt"
 	"io/ioutil"
 	"net/http"
 	"regexp"
 	"strings"
 	"sync/atomic"
 	"testing"
+	"time"
 
 	"github.com/ParsePlatform/parse-cli/parsecli"
 	"github.com/facebookgo/ensure"
@@ -110,3 +113,203 @@ func TestLogWithFollow(t *testing.T) {
 	ensure.DeepEqual(t, h.Err.String(), "")
 	ensure.DeepEqual(t, atomic.LoadInt64(&round), int64(4))
 }
+
+// logRound checks a request of follow mode and returns the rows to respond.
+type logRound func(r *http.Request) []logResponse
+
+// runLogFollow runs logs --follow with num messages per page against the
+// given rounds, the run stops with an error once they are exhausted.
+func runLogFollow(t testing.TB, num uint, rounds ...logRound) *parsecli.Harness {
+	h := parsecli.NewHarness(t)
+	var round int64
+	ht := parsecli.TransportFunc(func(r *http.Request) (*http.Response, error) {
+		n := int(atomic.AddInt64(&round, 1))
+		if n > len(rounds) {
+			return &http.Response{
+				StatusCode: http.StatusInternalServerError,
+				Body:       ioutil.NopCloser(strings.NewReader("done")),
+			}, nil
+		}
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Body:       ioutil.NopCloser(strings.NewReader(jsonStr(t, rounds[n-1](r)))),
+		}, nil
+	})
+	h.Env.ParseAPIClient = &parsecli.ParseAPIClient{APIClient: &parse.Client{Transport: ht}}
+
+	stop := make(chan struct{})
+	defer close(stop)
+	go func() {
+		for {
+			select {
+			case <-stop:
+				return
+			default:
+				h.Clock.Add(logFollowSleepDuration)
+			}
+		}
+	}()
+
+	l := logsCmd{level: "INFO", follow: true, num: num}
+	err := l.run(h.Env, &parsecli.Context{})
+	ensure.Err(t, err, regexp.MustCompile(`body="done"`))
+	ensure.DeepEqual(t, atomic.LoadInt64(&round), int64(len(rounds)+1))
+	return h
+}
+
+func logAt(iso, message string) logResponse {
+	return logResponse{Timestamp: parseTime{Type: "Date", ISO: iso}, Message: message}
+}
+
+func TestLogFollowDeduplicates(t *testing.T) {
+	t.Parallel()
+	const t1 = "2015-06-01T10:00:00.000Z"
+	h := runLogFollow(t, 10,
+		func(r *http.Request) []logResponse {
+			return []logResponse{logAt(t1, "b"), logAt(t1, "a")}
+		},
+		func(r *http.Request) []logResponse {
+			// asked again for the messages logged just before the newest one
+			ensure.DeepEqual(t, r.FormValue("startTime"),
+				`{"__type":"Date","iso":"2015-06-01T09:59:55.000Z"}`)
+			// c was logged at the same time as a and b, after the first round
+			return []logResponse{logAt(t1, "c"), logAt(t1, "b"), logAt(t1, "a")}
+		},
+		func(r *http.Request) []logResponse {
+			// a message logged twice is printed twice
+			return []logResponse{
+				logAt("2015-06-01T10:00:01.000Z", "c"),
+				logAt("2015-06-01T10:00:01.000Z", "c"),
+				logAt(t1, "c"), logAt(t1, "b"), logAt(t1, "a"),
+			}
+		},
+		func(r *http.Request) []logResponse {
+			ensure.DeepEqual(t, r.FormValue("startTime"),
+				`{"__type":"Date","iso":"2015-06-01T09:59:56.000Z"}`)
+			return []logResponse{
+				logAt("2015-06-01T10:00:01.000Z", "c"),
+				logAt("2015-06-01T10:00:01.000Z", "c"),
+			}
+		},
+	)
+	defer h.Stop()
+	ensure.DeepEqual(t, h.Out.String(), "a\nb\nc\nc\nc\n")
+	ensure.DeepEqual(t, h.Err.String(), "")
+}
+
+func TestLogFollowFetchesFullPagesAgain(t *testing.T) {
+	t.Parallel()
+	h := runLogFollow(t, 2,
+		func(r *http.Request) []logResponse {
+			return []logResponse{logAt("2015-06-01T10:00:00.001Z", "b"), logAt("2015-06-01T10:00:00.000Z", "a")}
+		},
+		func(r *http.Request) []logResponse {
+			ensure.DeepEqual(t, r.FormValue("n"), "2")
+			return []logResponse{logAt("2015-06-01T10:00:00.004Z", "e"), logAt("2015-06-01T10:00:00.003Z", "d")}
+		},
+		func(r *http.Request) []logResponse {
+			// the full page may have missed messages, this one reaches b
+			ensure.DeepEqual(t, r.FormValue("n"), "4")
+			return []logResponse{
+				logAt("2015-06-01T10:00:00.004Z", "e"),
+				logAt("2015-06-01T10:00:00.003Z", "d"),
+				logAt("2015-06-01T10:00:00.002Z", "c"),
+				logAt("2015-06-01T10:00:00.001Z", "b"),
+			}
+		},
+		func(r *http.Request) []logResponse {
+			// a full page reaching e is complete
+			ensure.DeepEqual(t, r.FormValue("n"), "2")
+			return []logResponse{logAt("2015-06-01T10:00:00.005Z", "f"), logAt("2015-06-01T10:00:00.004Z", "e")}
+		},
+	)
+	defer h.Stop()
+	ensure.DeepEqual(t, h.Out.String(), "a\nb\nc\nd\ne\nf\n")
+	ensure.DeepEqual(t, h.Err.String(), "")
+}
+
+func TestLogFollowFetchesFullPagesAtSameTime(t *testing.T) {
+	t.Parallel()
+	const t1 = "2015-06-01T10:00:00.000Z"
+	h := runLogFollow(t, 2,
+		func(r *http.Request) []logResponse {
+			return []logResponse{logAt(t1, "b"), logAt(t1, "a")}
+		},
+		func(r *http.Request) []logResponse {
+			// messages logged at the same time are not ordered, so the page
+			// may not hold all the new ones
+			ensure.DeepEqual(t, r.FormValue("n"), "2")
+			return []logResponse{logAt(t1, "d"), logAt(t1, "c")}
+		},
+		func(r *http.Request) []logResponse {
+			// this one holds all the messages printed at that time
+			ensure.DeepEqual(t, r.FormValue("n"), "4")
+			return []logResponse{logAt(t1, "d"), logAt(t1, "c"), logAt(t1, "b"), logAt(t1, "a")}
+		},
+		func(r *http.Request) []logResponse {
+			ensure.DeepEqual(t, r.FormValue("n"), "2")
+			return []logResponse{logAt("2015-06-01T10:00:01.000Z", "e")}
+		},
+	)
+	defer h.Stop()
+	ensure.DeepEqual(t, h.Out.String(), "a\nb\nc\nd\ne\n")
+	ensure.DeepEqual(t, h.Err.String(), "")
+}
+
+// logsFrom returns num messages logged every millisecond from the given one,
+// newest first.
+func logsFrom(first, num int) []logResponse {
+	start := time.Date(2015, 6, 1, 10, 0, 0, 0, time.UTC)
+	var rows []logResponse
+	for i := first + num - 1; i >= first; i-- {
+		rows = append(rows, logAt(
+			start.Add(time.Duration(i)*time.Millisecond).Format(parseISOFormat),
+			fmt.Sprint(i),
+		))
+	}
+	return rows
+}
+
+func TestLogFollowHighRate(t *testing.T) {
+	t.Parallel()
+	var (
+		rounds []logRound
+		want   bytes.Buffer
+	)
+	// every page is full, but overlaps the previous one
+	for i := 0; i < 20; i++ {
+		first := i * 9
+		rounds = append(rounds, func(r *http.Request) []logResponse {
+			ensure.DeepEqual(t, r.FormValue("n"), "10")
+			return logsFrom(first, 10)
+		})
+	}
+	for i := 0; i < 20*9+1; i++ {
+		fmt.Fprintln(&want, i)
+	}
+	h := runLogFollow(t, 10, rounds...)
+	defer h.Stop()
+	ensure.DeepEqual(t, h.Out.String(), want.String())
+	ensure.DeepEqual(t, h.Err.String(), "")
+}
+
+func TestLogFollowWarnsAboutMissedMessages(t *testing.T) {
+	t.Parallel()
+	h := runLogFollow(t, logMaxNum/2,
+		func(r *http.Request) []logResponse {
+			return logsFrom(0, 1)
+		},
+		func(r *http.Request) []logResponse {
+			return logsFrom(10, logMaxNum/2)
+		},
+		func(r *http.Request) []logResponse {
+			ensure.DeepEqual(t, r.FormValue("n"), fmt.Sprint(logMaxNum))
+			return logsFrom(10, logMaxNum)
+		},
+	)
+	defer h.Stop()
+	ensure.DeepEqual(t, h.Err.String(), fmt.Sprintf(
+		"More than %d messages were logged since the last round, some of them are not shown.\n",
+		logMaxNum,
+	))
+}
@@ -35,6 +35,14 @@ func base64MD5OfFile(filename string) (string, error) {
 }
 
 func uploadSymbolFiles(files []string, commonHeaders map[string]string, removeFiles bool, e *parsecli.Env) error {
+	var size int64
+	for _, file := range files {
+		if info, err := os.Stat(file); err == nil {
+			size += info.Size()
+		}
+	}
+	transfer := e.Progress.Start("Uploading symbol files", len(files), size)
+
 	var wg errgroup.Group
 	uploadFile := func(filename string, e *parsecli.Env) {
 		defer wg.Done()
@@ -46,7 +54,7 @@ func uploadSymbolFiles(files []string, commonHeaders map[string]string, removeFi
 		}
 		defer file.Close()
 
-		req, err := http.NewRequest("POST", path.Join("symbolFiles", name), bufio.NewReader(file))
+		req, err := http.NewRequest("POST", path.Join("symbolFiles", name), bufio.NewReader(transfer.Reader(file)))
 		if err != nil {
 			wg.Error(stackerr.Wrap(err))
 			return
@@ -74,6 +82,7 @@ func uploadSymbolFiles(files []string, commonHeaders map[string]string, removeFi
 			wg.Error(err)
 			return
 		}
+		transfer.FileDone()
 		if removeFiles {
 			if err := file.Close(); err != nil {
 				wg.Error(err)
@@ -90,6 +99,7 @@ func uploadSymbolFiles(files []string, commonHeaders map[string]string, removeFi
 		go uploadFile(file, e)
 	}
 	err := wg.Wait()
+	transfer.Finish()
 	if err != nil {
 		return err
 	}
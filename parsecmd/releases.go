@@ -4,7 +4,9 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/url"
+	"path"
 	"sort"
+	"strconv"
 	"strings"
 	"text/tabwriter"
 
@@ -29,6 +31,10 @@ type releasesCmd struct {
 	version string
 }
 
+type releasesDiffCmd struct {
+	content bool
+}
+
 func (r *releasesCmd) printFileNames(
 	fileVersions map[string]interface{},
 	e *parsecli.Env) {
@@ -40,9 +46,8 @@ func (r *releasesCmd) printFileNames(
 	fmt.Fprintln(e.Out, strings.Join(files, "\n"))
 }
 
-func (r *releasesCmd) printFiles(version string,
-	releases []releasesResponse,
-	e *parsecli.Env) error {
+// releaseFiles decodes the files deployed in the given release version.
+func releaseFiles(version string, releases []releasesResponse) (*userFiles, error) {
 	var files string
 	for _, release := range releases {
 		if release.Version == version {
@@ -51,7 +56,7 @@ func (r *releasesCmd) printFiles(version string,
 		}
 	}
 	if files == "" {
-		return stackerr.Newf(`Unable to fetch files for release version: %s
+		return nil, stackerr.Newf(`Unable to fetch files for release version: %s
 Note that you can list files for all releases shown in "parse releases"`,
 			version)
 	}
@@ -59,7 +64,64 @@ Note that you can list files for all releases shown in "parse releases"`,
 	if err := json.NewDecoder(
 		strings.NewReader(files),
 	).Decode(&versionFileNames); err != nil {
-		return stackerr.Wrap(err)
+		return nil, stackerr.Wrap(err)
+	}
+	return &versionFileNames, nil
+}
+
+func fetchReleases(e *parsecli.Env) ([]releasesResponse, error) {
+	u := &url.URL{
+		Path: "releases",
+	}
+	var releasesList []releasesResponse
+	if _, err := e.ParseAPIClient.Get(u, &releasesList); err != nil {
+		return nil, stackerr.Wrap(err)
+	}
+	return releasesList, nil
+}
+
+// stringVersions returns the file versions of a release as strings. Numeric
+// versions are decoded as float64, and are formatted without an exponent to
+// match the versions recorded as strings.
+func stringVersions(fileVersions map[string]interface{}) map[string]string {
+	versions := make(map[string]string, len(fileVersions))
+	for name, version := range fileVersions {
+		if v, ok := version.(float64); ok {
+			versions[name] = strconv.FormatFloat(v, 'f', -1, 64)
+			continue
+		}
+		versions[name] = fmt.Sprint(version)
+	}
+	return versions
+}
+
+// releaseDeployInfo builds the deploy info for the given release version from
+// the releases listing. The listing does not include checksums, so only the
+// file versions are populated.
+func releaseDeployInfo(e *parsecli.Env, version string) (*deployInfo, error) {
+	releases, err := fetchReleases(e)
+	if err != nil {
+		return nil, err
+	}
+	files, err := releaseFiles(version, releases)
+	if err != nil {
+		return nil, err
+	}
+	return &deployInfo{
+		ReleaseName: version,
+		Versions: deployFileData{
+			Cloud:  stringVersions(files.Cloud),
+			Public: stringVersions(files.Public),
+		},
+	}, nil
+}
+
+func (r *releasesCmd) printFiles(version string,
+	releases []releasesResponse,
+	e *parsecli.Env) error {
+	versionFileNames, err := releaseFiles(version, releases)
+	if err != nil {
+		return err
 	}
 	if len(versionFileNames.Cloud) != 0 {
 		fmt.Fprintf(e.Out, "Deployed Cloud Code files:\n")
@@ -76,32 +138,147 @@ Note that you can list files for all releases shown in "parse releases"`,
 }
 
 func (r *releasesCmd) run(e *parsecli.Env, c *parsecli.Context) error {
-	u := &url.URL{
-		Path: "releases",
-	}
-	var releasesList []releasesResponse
-	if _, err := e.ParseAPIClient.Get(u, &releasesList); err != nil {
-		return stackerr.Wrap(err)
+	releasesList, err := fetchReleases(e)
+	if err != nil {
+		return err
 	}
 
 	if r.version != "" {
 		return r.printFiles(r.version, releasesList, e)
 	}
 
+	// the commit column is only shown once releases are deployed from git
+	withCommits := false
+	for _, release := range releasesList {
+		if _, commit := parsecli.ParseCommitDescription(release.Description); commit != nil {
+			withCommits = true
+			break
+		}
+	}
+
 	w := new(tabwriter.Writer)
 	w.Init(e.Out, 32, 8, 0, ' ', 0)
-	fmt.Fprintln(w, "Name\tDescription\tDate")
+	if withCommits {
+		fmt.Fprintln(w, "Name\tDescription\tCommit\tDate")
+	} else {
+		fmt.Fprintln(w, "Name\tDescription\tDate")
+	}
 	for _, release := range releasesList {
-		description := "No release notes given"
-		if release.Description != "" {
-			description = release.Description
+		description, commit := parsecli.ParseCommitDescription(release.Description)
+		if description == "" {
+			description = "No release notes given"
+		}
+		if !withCommits {
+			fmt.Fprintf(w, "%s\t%s\t%s\n", release.Version, description, release.Timestamp)
+			continue
+		}
+		commitName := "-"
+		if commit != nil {
+			commitName = commit.String()
 		}
-		fmt.Fprintf(w, "%s\t%s\t%s\n", release.Version, description, release.Timestamp)
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", release.Version, description, commitName, release.Timestamp)
 	}
 	w.Flush()
 	return nil
 }
 
+func (r *releasesDiffCmd) printContentDiffs(
+	target deployTarget,
+	fromName, toName string,
+	from, to map[string]string,
+	changes *changeSet,
+	e *parsecli.Env,
+) error {
+	fetch := func(name, version string) ([]byte, error) {
+		return target.fetch(e, name, version, "")
+	}
+
+	var names []string
+	names = append(names, changes.Modified...)
+	names = append(names, changes.Added...)
+	names = append(names, changes.Removed...)
+	for _, name := range names {
+		var fromContent, toContent []byte
+		fromPath, toPath := "/dev/null", "/dev/null"
+		if version, ok := from[name]; ok {
+			content, err := fetch(name, version)
+			if err != nil {
+				return err
+			}
+			fromContent, fromPath = content, path.Join(fromName, target.Name, name)
+		}
+		if version, ok := to[name]; ok {
+			content, err := fetch(name, version)
+			if err != nil {
+				return err
+			}
+			toContent, toPath = content, path.Join(toName, target.Name, name)
+		}
+		fmt.Fprint(e.Out, unifiedDiff(fromPath, toPath, fromContent, toContent))
+	}
+	return nil
+}
+
+func (r *releasesDiffCmd) run(e *parsecli.Env, c *parsecli.Context, args []string) error {
+	fromName, toName := args[0], args[1]
+	releases, err := fetchReleases(e)
+	if err != nil {
+		return err
+	}
+	fromFiles, err := releaseFiles(fromName, releases)
+	if err != nil {
+		return err
+	}
+	toFiles, err := releaseFiles(toName, releases)
+	if err != nil {
+		return err
+	}
+
+	targets := []struct {
+		target   deployTarget
+		from, to map[string]interface{}
+	}{
+		{scriptsTarget, fromFiles.Cloud, toFiles.Cloud},
+		{hostingTarget, fromFiles.Public, toFiles.Public},
+	}
+
+	differ := false
+	for _, t := range targets {
+		from, to := stringVersions(t.from), stringVersions(t.to)
+		changes := diffChecksums(from, to)
+		if !changes.changed() {
+			continue
+		}
+		if differ {
+			fmt.Fprintln(e.Out)
+		}
+		differ = true
+
+		fmt.Fprintf(e.Out, "Changes from %s to %s in %s:\n", fromName, toName, t.target.Name)
+		for _, name := range changes.Added {
+			fmt.Fprintf(e.Out, "  added:   %s\n", name)
+		}
+		for _, name := range changes.Removed {
+			fmt.Fprintf(e.Out, "  removed: %s\n", name)
+		}
+		for _, name := range changes.Modified {
+			fmt.Fprintf(e.Out, "  changed: %s (version %s -> %s)\n", name, from[name], to[name])
+		}
+
+		if r.content {
+			fmt.Fprintln(e.Out)
+			if err := r.printContentDiffs(t.target, fromName, toName, from, to, changes, e); err != nil {
+				return err
+			}
+		}
+	}
+
+	if !differ {
+		fmt.Fprintf(e.Out, "No differences between releases %s and %s.\n", fromName, toName)
+	}
+	return nil
+}
+
 func NewReleasesCmd(e *parsecli.Env) *cobra.Command {
 	r := &releasesCmd{}
 	cmd := &cobra.Command{
@@ -112,5 +289,17 @@ func NewReleasesCmd(e *parsecli.Env) *cobra.Command {
 	}
 	cmd.Flags().StringVarP(&r.version, "version", "v", r.version,
 		"List files names of the deployed version.")
+
+	d := &releasesDiffCmd{}
+	diffCmd := &cobra.Command{
+		Use:   "diff releaseA releaseB [app]",
+		Short: "Compares the files of two releases",
+		Long: `Lists the files added, removed and changed between two releases
+shown in "parse releases".`,
+		Run: parsecli.RunWithClientArgs(e, 2, d.run),
+	}
+	diffCmd.Flags().BoolVarP(&d.content, "content", "c", d.content,
+		"Fetch changed files and show their content diffs.")
+	cmd.AddCommand(diffCmd)
 	return cmd
 }
@@ -1,6 +1,7 @@
 package parsecmd
 
 import (
+	"encoding/json"
 	"io/ioutil"
 	"net/http"
 	"regexp"
@@ -45,6 +46,34 @@ v2                              version 2                       time 2
 	ensure.DeepEqual(t, h.Out.String(), expected)
 }
 
+func TestReleasesCmdWithCommits(t *testing.T) {
+	h, r := newReleasesCmdHarness(t)
+	defer h.Stop()
+	rows := []releasesResponse{
+		{Version: "v1", Description: "version 1", Timestamp: "time 1"},
+		{
+			Version:     "v2",
+			Description: "version 2 [git 0123456789abcdef0123456789abcdef01234567 main dirty]",
+			Timestamp:   "time 2",
+		},
+	}
+	ht := parsecli.TransportFunc(func(r *http.Request) (*http.Response, error) {
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Body:       ioutil.NopCloser(strings.NewReader(jsonStr(t, rows))),
+		}, nil
+	})
+	h.Env.ParseAPIClient = &parsecli.ParseAPIClient{APIClient: &parse.Client{Transport: ht}}
+
+	ensure.Nil(t, r.run(h.Env, &parsecli.Context{}))
+
+	expected := `Name                            Description                     Commit                          Date
+v1                              version 1                       -                               time 1
+v2                              version 2                       0123456 (main, dirty)           time 2
+`
+	ensure.DeepEqual(t, h.Out.String(), expected)
+}
+
 func TestReleasesCmdError(t *testing.T) {
 	h, c := newReleasesCmdHarness(t)
 	defer h.Stop()
@@ -102,3 +131,118 @@ docs.html
 index.html
 `)
 }
+
+func newReleasesDiffHarness(t testing.TB) *parsecli.Harness {
+	h := parsecli.NewHarness(t)
+	h.MakeEmptyRoot()
+	releases := []releasesResponse{
+		{Version: "v1",
+			UserFiles: `{
+			"cloud": {"main.js": "1", "app.js": "1", "views/index.js": "1"}
+			}`,
+		},
+		{Version: "v2",
+			UserFiles: `{
+			"cloud": {"main.js": "2", "app.js": "1", "views/docs.js": "1"},
+			"public": {"index.html": "2"}
+			}`,
+		},
+	}
+	ht := parsecli.TransportFunc(func(r *http.Request) (*http.Response, error) {
+		var body string
+		switch r.URL.Path {
+		case "/1/releases":
+			body = jsonStr(t, releases)
+		case "/1/scripts/main.js":
+			body = jsonStr(t, "version "+r.FormValue("version")+"\n")
+		case "/1/scripts/views/index.js", "/1/scripts/views/docs.js":
+			body = jsonStr(t, "view\n")
+		case "/1/hosted_files/index.html":
+			body = jsonStr(t, []byte("<html></html>\n"))
+		default:
+			return &http.Response{
+				StatusCode: http.StatusNotFound,
+				Body:       ioutil.NopCloser(strings.NewReader(`{"error": "something is wrong"}`)),
+			}, nil
+		}
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Body:       ioutil.NopCloser(strings.NewReader(body)),
+		}, nil
+	})
+	h.Env.ParseAPIClient = &parsecli.ParseAPIClient{APIClient: &parse.Client{Transport: ht}}
+	return h
+}
+
+func TestReleasesDiff(t *testing.T) {
+	t.Parallel()
+	h := newReleasesDiffHarness(t)
+	defer h.Stop()
+
+	var r releasesDiffCmd
+	ensure.Nil(t, r.run(h.Env, &parsecli.Context{}, []string{"v1", "v2"}))
+	ensure.DeepEqual(t, h.Out.String(), `Changes from v1 to v2 in cloud:
+  added:   views/docs.js
+  removed: views/index.js
+  changed: main.js (version 1 -> 2)
+
+Changes from v1 to v2 in public:
+  added:   index.html
+`)
+
+	h.Out.Reset()
+	ensure.Nil(t, r.run(h.Env, &parsecli.Context{}, []string{"v1", "v1"}))
+	ensure.DeepEqual(t, h.Out.String(), "No differences between releases v1 and v1.\n")
+
+	ensure.Err(t,
+		r.run(h.Env, &parsecli.Context{}, []string{"v1", "v3"}),
+		regexp.MustCompile("Unable to fetch files for release version: v3"),
+	)
+}
+
+func TestReleasesDiffContent(t *testing.T) {
+	t.Parallel()
+	h := newReleasesDiffHarness(t)
+	defer h.Stop()
+
+	r := releasesDiffCmd{content: true}
+	ensure.Nil(t, r.run(h.Env, &parsecli.Context{}, []string{"v1", "v2"}))
+	ensure.DeepEqual(t, h.Out.String(), `Changes from v1 to v2 in cloud:
+  added:   views/docs.js
+  removed: views/index.js
+  changed: main.js (version 1 -> 2)
+
+--- v1/cloud/main.js
++++ v2/cloud/main.js
+@@ -1,1 +1,1 @@
+-version 1
++version 2
+--- /dev/null
++++ v2/cloud/views/docs.js
+@@ -0,0 +1,1 @@
++view
+--- v1/cloud/views/index.js
++++ /dev/null
+@@ -1,1 +0,0 @@
+-view
+
+Changes from v1 to v2 in public:
+  added:   index.html
+
+--- /dev/null
++++ v2/public/index.html
+@@ -0,0 +1,1 @@
++<html></html>
+`)
+}
+
+func TestStringVersions(t *testing.T) {
+	t.Parallel()
+	var versions map[string]interface{}
+	ensure.Nil(t, json.Unmarshal([]byte(`{"a.js": 1000000, "b.js": "f2", "c.js": 12}`), &versions))
+	ensure.DeepEqual(t, stringVersions(versions), map[string]string{
+		"a.js": "1000000",
+		"b.js": "f2",
+		"c.js": "12",
+	})
+}
@@ -11,6 +11,7 @@ import (
 
 type rollbackCmd struct {
 	ReleaseName string
+	Confirmed   bool
 }
 
 type rollbackInfo struct {
@@ -18,6 +19,10 @@ type rollbackInfo struct {
 }
 
 func (r *rollbackCmd) run(e *parsecli.Env, c *parsecli.Context) error {
+	if err := parsecli.ConfirmProtectedApp(e, c, "rollback", r.Confirmed); err != nil {
+		return err
+	}
+
 	var req rollbackInfo
 	message := "previous release"
 	if r.ReleaseName != "" {
@@ -46,5 +51,7 @@ func NewRollbackCmd(e *parsecli.Env) *cobra.Command {
 	}
 	cmd.Flags().StringVarP(&r.ReleaseName, "release", "r", r.ReleaseName,
 		"Provides an optional release to rollback to. If no release is provided, rolls back to the previous release.")
+	cmd.Flags().BoolVar(&r.Confirmed, parsecli.ConfirmProtectedFlag, r.Confirmed,
+		"Roll back protected apps without asking for confirmation")
 	return cmd
 }
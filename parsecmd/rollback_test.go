@@ -74,3 +74,47 @@ func TestRollbackError(t *testing.T) {
 	h.Env.ParseAPIClient = &parsecli.ParseAPIClient{APIClient: &parse.Client{Transport: ht}}
 	ensure.Err(t, r.run(h.Env, nil), regexp.MustCompile("something is wrong"))
 }
+
+func protectedContext(name string) *parsecli.Context {
+	return &parsecli.Context{
+		Config:    defaultParseConfig,
+		AppName:   name,
+		AppConfig: &parsecli.ParseAppConfig{ApplicationID: "id", Protected: true},
+	}
+}
+
+func TestRollbackProtectedConfirmed(t *testing.T) {
+	t.Parallel()
+	var r rollbackCmd
+	h := newRollbackCmdHarness(t)
+	defer h.Stop()
+	h.Env.In = strings.NewReader("production\n")
+	ensure.Nil(t, r.run(h.Env, protectedContext("production")))
+	ensure.StringContains(t, h.Out.String(), `App "production" is protected.`)
+	ensure.StringContains(t, h.Out.String(), "Rolled back to version v0")
+}
+
+func TestRollbackProtectedNotConfirmed(t *testing.T) {
+	t.Parallel()
+	var r rollbackCmd
+	h := parsecli.NewHarness(t)
+	defer h.Stop()
+	h.Env.ParseAPIClient = &parsecli.ParseAPIClient{APIClient: &parse.Client{
+		Transport: parsecli.TransportFunc(func(r *http.Request) (*http.Response, error) {
+			t.Fatalf("unexpected request to %s", r.URL.Path)
+			return nil, nil
+		}),
+	}}
+	h.Env.In = strings.NewReader("staging\n")
+	ensure.Err(t, r.run(h.Env, protectedContext("production")),
+		regexp.MustCompile(`The rollback was not confirmed, nothing was changed on app "production".`))
+}
+
+func TestRollbackProtectedFlag(t *testing.T) {
+	t.Parallel()
+	r := rollbackCmd{Confirmed: true}
+	h := newRollbackCmdHarness(t)
+	defer h.Stop()
+	ensure.Nil(t, r.run(h.Env, protectedContext("production")))
+	ensure.StringDoesNotContain(t, h.Out.String(), "protected")
+}
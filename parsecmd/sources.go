@@ -0,0 +1,207 @@
+package parsecmd
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/ParsePlatform/parse-cli/parsecli"
+	"github.com/facebookgo/stackerr"
+)
+
+// deployTarget is a kind of file deployed to Parse, either Cloud Code scripts
+// or hosted files.
+type deployTarget struct {
+	// Name is the key of the files in a release, it is also the directory the
+	// files are deployed from by default.
+	Name     string
+	Message  string
+	EndPoint string
+}
+
+var (
+	scriptsTarget = deployTarget{Name: parsecli.CloudDir, Message: "scripts", EndPoint: "scripts"}
+	hostingTarget = deployTarget{Name: parsecli.HostingDir, Message: "hosting", EndPoint: "hosted_files"}
+	deployTargets = []deployTarget{scriptsTarget, hostingTarget}
+)
+
+// checksums returns the checksums recorded in the release for the target.
+func (t deployTarget) checksums(info *deployInfo) map[string]string {
+	if t.Name == parsecli.CloudDir {
+		return info.Checksums.Cloud
+	}
+	return info.Checksums.Public
+}
+
+// versions returns the file versions recorded in the release for the target.
+func (t deployTarget) versions(info *deployInfo) map[string]string {
+	if t.Name == parsecli.CloudDir {
+		return info.Versions.Cloud
+	}
+	return info.Versions.Public
+}
+
+// fetch downloads the content of a deployed file of the target.
+func (t deployTarget) fetch(e *parsecli.Env, file, version, checksum string) ([]byte, error) {
+	if t.Name == parsecli.CloudDir {
+		return fetchScript(e, file, version, checksum)
+	}
+	return fetchHosted(e, file, version, checksum)
+}
+
+// scriptSuffixes are the file extensions uploaded from the cloud directory.
+var scriptSuffixes = map[string]struct{}{
+	".js":   {},
+	".ejs":  {},
+	".jade": {},
+}
+
+// sourceDir describes a directory of the project that is deployed to Parse.
+type sourceDir struct {
+	DirName  string
+	Suffixes map[string]struct{}
+	Target   deployTarget
+}
+
+// accepts reports whether the file with the given name is deployed from the
+// directory.
+func (s sourceDir) accepts(name string) bool {
+	if len(s.Suffixes) == 0 {
+		return true
+	}
+	_, ok := s.Suffixes[filepath.Ext(name)]
+	return ok
+}
+
+type sourceDirList []sourceDir
+
+var defaultSourceDirs = sourceDirList{
+	{DirName: parsecli.CloudDir, Suffixes: scriptSuffixes, Target: scriptsTarget},
+	{DirName: parsecli.HostingDir, Suffixes: map[string]struct{}{}, Target: hostingTarget},
+}
+
+// projectSourceDirs returns the source directories configured for the
+// project, or the default ones if none are configured.
+func projectSourceDirs(config *parsecli.ProjectConfig) (sourceDirList, error) {
+	if config == nil || config.Parse == nil || len(config.Parse.Sources) == 0 {
+		return defaultSourceDirs, nil
+	}
+
+	var dirs sourceDirList
+	for _, source := range config.Parse.Sources {
+		var target deployTarget
+		switch source.Target {
+		case scriptsTarget.Name:
+			target = scriptsTarget
+		case hostingTarget.Name:
+			target = hostingTarget
+		default:
+			return nil, stackerr.Newf(
+				"Unknown target %q for source directory %q in %s, expected %q or %q.",
+				source.Target,
+				source.Dir,
+				parsecli.ParseProject,
+				scriptsTarget.Name,
+				hostingTarget.Name,
+			)
+		}
+		dir := filepath.Clean(filepath.FromSlash(source.Dir))
+		if source.Dir == "" || filepath.IsAbs(dir) || dir == ".." ||
+			strings.HasPrefix(dir, ".."+string(filepath.Separator)) {
+			return nil, stackerr.Newf(
+				"Source directory %q in %s must be a directory inside the project.",
+				source.Dir,
+				parsecli.ParseProject,
+			)
+		}
+
+		suffixes := make(map[string]struct{})
+		for _, suffix := range source.Suffixes {
+			if !strings.HasPrefix(suffix, ".") {
+				suffix = "." + suffix
+			}
+			suffixes[suffix] = struct{}{}
+		}
+		dirs = append(dirs, sourceDir{DirName: dir, Suffixes: suffixes, Target: target})
+	}
+	return dirs, nil
+}
+
+// forTarget returns the directories deployed to the given target.
+func (l sourceDirList) forTarget(t deployTarget) sourceDirList {
+	var dirs sourceDirList
+	for _, dir := range l {
+		if dir.Target.Name == t.Name {
+			dirs = append(dirs, dir)
+		}
+	}
+	return dirs
+}
+
+// localPath returns the path under root where a deployed file of the target
+// lives: in the first directory of the target that accepts the file, or in the
+// first directory of the target if none does.
+func (l sourceDirList) localPath(root string, t deployTarget, name string) string {
+	dirName := t.Name
+	dirs := l.forTarget(t)
+	if len(dirs) != 0 {
+		dirName = dirs[0].DirName
+	}
+	for _, dir := range dirs {
+		if dir.accepts(name) {
+			dirName = dir.DirName
+			break
+		}
+	}
+	return filepath.Join(root, dirName, filepath.FromSlash(name))
+}
+
+// targetFiles returns the local files deployed to the target, keyed by the
+// name they are deployed as.
+func (d *deployCmd) targetFiles(t deployTarget, e *parsecli.Env) (map[string]string, error) {
+	files := make(map[string]string)
+	for _, dir := range d.sourceDirs().forTarget(t) {
+		root := filepath.Join(e.Root, dir.DirName)
+		sourceFiles, _, err := d.getSourceFiles(root, dir.Suffixes, e)
+		if err != nil {
+			if stackerr.HasUnderlying(err, stackerr.MatcherFunc(os.IsNotExist)) {
+				continue
+			}
+			return nil, err
+		}
+		normalizeName := relativeNamer(root)
+		for _, file := range sourceFiles {
+			name := normalizeName(file)
+			if other, ok := files[name]; ok {
+				return nil, stackerr.Newf(
+					"Both %s and %s would be deployed as %s/%s.",
+					other,
+					file,
+					t.Name,
+					name,
+				)
+			}
+			files[name] = file
+		}
+	}
+	return files, nil
+}
+
+// targetChecksums computes the checksums of the local files that would be
+// deployed to the target.
+func (d *deployCmd) targetChecksums(t deployTarget, e *parsecli.Env) (map[string]string, error) {
+	if d.source != nil {
+		return d.source.Manifest.checksums(t.Name), nil
+	}
+	files, err := d.targetFiles(t, e)
+	if err != nil {
+		return nil, err
+	}
+	names := make(map[string]string, len(files))
+	paths := make([]string, 0, len(files))
+	for name, file := range files {
+		names[file] = name
+		paths = append(paths, file)
+	}
+	return d.computeChecksums(paths, func(file string) string { return names[file] })
+}
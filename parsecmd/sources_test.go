@@ -0,0 +1,141 @@
+package parsecmd
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"testing"
+
+	"github.com/ParsePlatform/parse-cli/parsecli"
+	"github.com/facebookgo/ensure"
+)
+
+func customSourcesConfig(sources ...*parsecli.SourceConfig) *parsecli.ProjectConfig {
+	return &parsecli.ProjectConfig{
+		Type:  parsecli.ParseFormat,
+		Parse: &parsecli.ParseProjectConfig{Sources: sources},
+	}
+}
+
+func TestProjectSourceDirsDefault(t *testing.T) {
+	t.Parallel()
+	dirs, err := projectSourceDirs(defaultParseConfig.GetProjectConfig())
+	ensure.Nil(t, err)
+	ensure.DeepEqual(t, dirs, defaultSourceDirs)
+}
+
+func TestProjectSourceDirsConfigured(t *testing.T) {
+	t.Parallel()
+	dirs, err := projectSourceDirs(customSourcesConfig(
+		&parsecli.SourceConfig{Dir: "cloud", Suffixes: []string{".js", "json"}, Target: "cloud"},
+		&parsecli.SourceConfig{Dir: "dist/site", Target: "public"},
+	))
+	ensure.Nil(t, err)
+	ensure.DeepEqual(t, dirs, sourceDirList{
+		{
+			DirName:  "cloud",
+			Suffixes: map[string]struct{}{".js": {}, ".json": {}},
+			Target:   scriptsTarget,
+		},
+		{
+			DirName:  filepath.Join("dist", "site"),
+			Suffixes: map[string]struct{}{},
+			Target:   hostingTarget,
+		},
+	})
+}
+
+func TestProjectSourceDirsInvalid(t *testing.T) {
+	t.Parallel()
+	_, err := projectSourceDirs(customSourcesConfig(
+		&parsecli.SourceConfig{Dir: "dist", Target: "hosting"},
+	))
+	ensure.Err(t, err, regexp.MustCompile(`Unknown target "hosting" for source directory "dist"`))
+
+	_, err = projectSourceDirs(customSourcesConfig(
+		&parsecli.SourceConfig{Dir: "../dist", Target: "public"},
+	))
+	ensure.Err(t, err, regexp.MustCompile(`must be a directory inside the project`))
+}
+
+func TestSourceDirsLocalPath(t *testing.T) {
+	t.Parallel()
+	dirs, err := projectSourceDirs(customSourcesConfig(
+		&parsecli.SourceConfig{Dir: "cloud", Suffixes: []string{".js"}, Target: "cloud"},
+		&parsecli.SourceConfig{Dir: "templates", Suffixes: []string{".hbs"}, Target: "cloud"},
+		&parsecli.SourceConfig{Dir: "dist", Target: "public"},
+	))
+	ensure.Nil(t, err)
+
+	ensure.DeepEqual(t, dirs.localPath("root", scriptsTarget, "views/a.hbs"),
+		filepath.Join("root", "templates", "views", "a.hbs"))
+	ensure.DeepEqual(t, dirs.localPath("root", scriptsTarget, "main.js"),
+		filepath.Join("root", "cloud", "main.js"))
+	ensure.DeepEqual(t, dirs.localPath("root", scriptsTarget, "data.json"),
+		filepath.Join("root", "cloud", "data.json"))
+	ensure.DeepEqual(t, dirs.localPath("root", hostingTarget, "index.html"),
+		filepath.Join("root", "dist", "index.html"))
+}
+
+func TestDeployConfiguredSources(t *testing.T) {
+	t.Parallel()
+	h := setupForDeploy(t, &deployInfo{})
+	defer h.Stop()
+
+	ensure.Nil(t, os.MkdirAll(filepath.Join(h.Env.Root, "dist", "css"), 0755))
+	ensure.Nil(t, ioutil.WriteFile(filepath.Join(h.Env.Root, "dist", "css", "site.css"), nil, 0600))
+	ensure.Nil(t, ioutil.WriteFile(filepath.Join(h.Env.Root, parsecli.CloudDir, "data.json"), nil, 0600))
+
+	dirs, err := projectSourceDirs(customSourcesConfig(
+		&parsecli.SourceConfig{Dir: "cloud", Suffixes: []string{".js", ".json"}, Target: "cloud"},
+		&parsecli.SourceConfig{Dir: "dist", Target: "public"},
+	))
+	ensure.Nil(t, err)
+
+	d := deployCmd{sources: dirs}
+	res, err := d.deploy("latest", nil, false, h.Env)
+	ensure.Nil(t, err)
+	ensure.DeepEqual(t, res.Versions, deployFileData{
+		Cloud:  map[string]string{"main.js": "f2", "data.json": "f2"},
+		Public: map[string]string{"css/site.css": "f2"},
+	})
+}
+
+func TestDeployConfiguredSourcesConflict(t *testing.T) {
+	t.Parallel()
+	h := setupForDeploy(t, &deployInfo{})
+	defer h.Stop()
+
+	ensure.Nil(t, os.MkdirAll(filepath.Join(h.Env.Root, "dist"), 0755))
+	ensure.Nil(t, ioutil.WriteFile(filepath.Join(h.Env.Root, "dist", "index.html"), nil, 0600))
+
+	dirs, err := projectSourceDirs(customSourcesConfig(
+		&parsecli.SourceConfig{Dir: "public", Target: "public"},
+		&parsecli.SourceConfig{Dir: "dist", Target: "public"},
+	))
+	ensure.Nil(t, err)
+
+	d := deployCmd{sources: dirs}
+	_, err = d.deploy("latest", nil, false, h.Env)
+	ensure.Err(t, err, regexp.MustCompile("public/index.html is deployed from more than one source directory"))
+}
+
+func TestDownloadConfiguredSources(t *testing.T) {
+	t.Parallel()
+	h, d := newDownloadHarness(t)
+	defer h.Stop()
+
+	dirs, err := projectSourceDirs(customSourcesConfig(
+		&parsecli.SourceConfig{Dir: "cloud", Target: "cloud"},
+		&parsecli.SourceConfig{Dir: "dist", Target: "public"},
+	))
+	ensure.Nil(t, err)
+	d.sources = dirs
+
+	ensure.Nil(t, d.downloadIntoProject(h.Env, d.release))
+	_, err = os.Stat(filepath.Join(h.Env.Root, "dist", "index.html"))
+	ensure.Nil(t, err)
+	_, err = os.Stat(filepath.Join(h.Env.Root, parsecli.CloudDir, "main.js"))
+	ensure.Nil(t, err)
+}
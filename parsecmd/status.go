@@ -0,0 +1,74 @@
+package parsecmd
+
+import (
+	"fmt"
+
+	"github.com/ParsePlatform/parse-cli/parsecli"
+	"github.com/spf13/cobra"
+)
+
+type statusCmd struct{}
+
+func (s *statusCmd) printChanges(dirName string, c *changeSet, e *parsecli.Env) {
+	fmt.Fprintf(e.Out, "Changes in %s:\n", dirName)
+	for _, name := range c.Modified {
+		fmt.Fprintf(e.Out, "  modified:        %s\n", name)
+	}
+	for _, name := range c.Added {
+		fmt.Fprintf(e.Out, "  new locally:     %s\n", name)
+	}
+	for _, name := range c.Removed {
+		fmt.Fprintf(e.Out, "  missing locally: %s\n", name)
+	}
+}
+
+func (s *statusCmd) run(e *parsecli.Env, c *parsecli.Context) error {
+	sources, err := projectSourceDirs(c.Config.GetProjectConfig())
+	if err != nil {
+		return err
+	}
+	d := &deployCmd{sources: sources}
+	release, err := d.getPrevDeplInfo(e)
+	if err != nil {
+		return err
+	}
+
+	releaseName := release.ReleaseName
+	if releaseName == "" {
+		releaseName = "the latest release"
+	}
+	fmt.Fprintf(e.Out, "Comparing local files with %s\n", releaseName)
+
+	changed := false
+	for _, target := range deployTargets {
+		checksums, err := d.targetChecksums(target, e)
+		if err != nil {
+			return err
+		}
+		changes := diffChecksums(target.checksums(release), checksums)
+		if !changes.changed() {
+			continue
+		}
+		changed = true
+		fmt.Fprintln(e.Out)
+		s.printChanges(target.Name, changes, e)
+	}
+
+	if !changed {
+		fmt.Fprintf(e.Out, "Local files are up to date with %s.\n", releaseName)
+	}
+	return nil
+}
+
+func NewStatusCmd(e *parsecli.Env) *cobra.Command {
+	s := &statusCmd{}
+	cmd := &cobra.Command{
+		Use:   "status [app]",
+		Short: "Compares local files with the deployed release",
+		Long: `Compares the files in the source directories of the project with the files
+in the latest release of the given app, and lists files that were modified,
+that only exist locally, or that are missing locally.`,
+		Run: parsecli.RunWithClient(e, s.run),
+	}
+	return cmd
+}
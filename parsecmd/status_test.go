@@ -0,0 +1,69 @@
+package parsecmd
+
+import (
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/ParsePlatform/parse-cli/parsecli"
+	"github.com/facebookgo/ensure"
+	"github.com/facebookgo/parse"
+)
+
+func newStatusHarness(t testing.TB, info *deployInfo) *parsecli.Harness {
+	h := createParseProject(t)
+	ht := parsecli.TransportFunc(func(r *http.Request) (*http.Response, error) {
+		ensure.DeepEqual(t, r.URL.Path, "/1/deploy")
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Body:       ioutil.NopCloser(strings.NewReader(jsonStr(t, info))),
+		}, nil
+	})
+	h.Env.ParseAPIClient = &parsecli.ParseAPIClient{APIClient: &parse.Client{Transport: ht}}
+	return h
+}
+
+func TestStatusChanged(t *testing.T) {
+	t.Parallel()
+	h := newStatusHarness(t, &deployInfo{
+		ReleaseName: "v1",
+		Checksums: deployFileData{
+			Cloud: map[string]string{
+				"main.js": "d41d8cd98f00b204e9800998ecf8427e",
+				"app.js":  "d41d8cd98f00b204e9800998ecf8427e",
+			},
+		},
+	})
+	defer h.Stop()
+
+	var s statusCmd
+	ensure.Nil(t, s.run(h.Env, &parsecli.Context{Config: defaultParseConfig}))
+	ensure.DeepEqual(t, h.Out.String(), `Comparing local files with v1
+
+Changes in cloud:
+  modified:        main.js
+  missing locally: app.js
+
+Changes in public:
+  new locally:     index.html
+`)
+}
+
+func TestStatusUpToDate(t *testing.T) {
+	t.Parallel()
+	h := newStatusHarness(t, &deployInfo{
+		ReleaseName: "v1",
+		Checksums: deployFileData{
+			Cloud:  map[string]string{"main.js": "4ece160cc8e5e828ee718e7367cf5d37"},
+			Public: map[string]string{"index.html": "9e2354a0ebac5852bc674026137c8612"},
+		},
+	})
+	defer h.Stop()
+
+	var s statusCmd
+	ensure.Nil(t, s.run(h.Env, &parsecli.Context{Config: defaultParseConfig}))
+	ensure.DeepEqual(t, h.Out.String(), `Comparing local files with v1
+Local files are up to date with v1.
+`)
+}
@@ -0,0 +1,219 @@
+package parsecmd
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+)
+
+const diffContextLines = 3
+
+// maxDiffEdits bounds the number of line edits of a diff, as computing it
+// takes memory quadratic in that number. Files changed more than that are
+// only reported as different.
+const maxDiffEdits = 1000
+
+type diffOp struct {
+	kind byte // ' ', '-' or '+'
+	line string
+}
+
+// splitLines splits content into lines, keeping the line terminators.
+func splitLines(content []byte) []string {
+	if len(content) == 0 {
+		return nil
+	}
+	lines := strings.SplitAfter(string(content), "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
+
+// isBinary reports whether content looks like binary data.
+func isBinary(content []byte) bool {
+	return bytes.IndexByte(content, 0) != -1
+}
+
+// diffLines computes the shortest edit script turning a into b using the
+// Myers diff algorithm. It returns false if it needs more than maxDiffEdits
+// edits.
+func diffLines(a, b []string) ([]diffOp, bool) {
+	n, m := len(a), len(b)
+	max := n + m
+	if n == 0 || m == 0 {
+		// files added or removed need no search
+		var ops []diffOp
+		for _, line := range a {
+			ops = append(ops, diffOp{'-', line})
+		}
+		for _, line := range b {
+			ops = append(ops, diffOp{'+', line})
+		}
+		return ops, true
+	}
+
+	// v[offset+k] holds the furthest x reached on diagonal k.
+	offset := max + 1
+	v := make([]int, 2*max+3)
+	var trace [][]int
+
+	found := false
+	for d := 0; d <= max && !found; d++ {
+		if d > maxDiffEdits {
+			return nil, false
+		}
+		snapshot := make([]int, 2*d+3)
+		copy(snapshot, v[offset-d-1:offset+d+2])
+		trace = append(trace, snapshot)
+
+		for k := -d; k <= d; k += 2 {
+			var x int
+			if k == -d || (k != d && v[offset+k-1] < v[offset+k+1]) {
+				x = v[offset+k+1]
+			} else {
+				x = v[offset+k-1] + 1
+			}
+			y := x - k
+			for x < n && y < m && a[x] == b[y] {
+				x++
+				y++
+			}
+			v[offset+k] = x
+			if x >= n && y >= m {
+				found = true
+				break
+			}
+		}
+	}
+
+	var ops []diffOp
+	x, y := n, m
+	for d := len(trace) - 1; d > 0; d-- {
+		// snapshot d holds the state after d-1 steps for diagonals -d-1..d+1
+		snapshot := trace[d]
+		at := func(k int) int { return snapshot[k+d+1] }
+
+		k := x - y
+		var prevK int
+		if k == -d || (k != d && at(k-1) < at(k+1)) {
+			prevK = k + 1
+		} else {
+			prevK = k - 1
+		}
+		prevX := at(prevK)
+		prevY := prevX - prevK
+
+		for x > prevX && y > prevY {
+			ops = append(ops, diffOp{' ', a[x-1]})
+			x--
+			y--
+		}
+		if x == prevX {
+			ops = append(ops, diffOp{'+', b[y-1]})
+			y--
+		} else {
+			ops = append(ops, diffOp{'-', a[x-1]})
+			x--
+		}
+	}
+	for x > 0 && y > 0 {
+		ops = append(ops, diffOp{' ', a[x-1]})
+		x--
+		y--
+	}
+
+	for i, j := 0, len(ops)-1; i < j; i, j = i+1, j-1 {
+		ops[i], ops[j] = ops[j], ops[i]
+	}
+	return ops, true
+}
+
+func writeDiffLine(b *bytes.Buffer, kind byte, line string) {
+	b.WriteByte(kind)
+	b.WriteString(line)
+	if !strings.HasSuffix(line, "\n") {
+		b.WriteString("\n\\ No newline at end of file\n")
+	}
+}
+
+// unifiedDiff returns the unified diff between two versions of a file, or an
+// empty string if they are identical.
+func unifiedDiff(fromName, toName string, from, to []byte) string {
+	if bytes.Equal(from, to) {
+		return ""
+	}
+	if isBinary(from) || isBinary(to) {
+		return fmt.Sprintf("Binary files %s and %s differ\n", fromName, toName)
+	}
+
+	ops, ok := diffLines(splitLines(from), splitLines(to))
+	if !ok {
+		return fmt.Sprintf("Files %s and %s differ in more than %d lines\n", fromName, toName, maxDiffEdits)
+	}
+
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "--- %s\n+++ %s\n", fromName, toName)
+
+	for start := 0; start < len(ops); {
+		// find the next change
+		first := start
+		for first < len(ops) && ops[first].kind == ' ' {
+			first++
+		}
+		if first == len(ops) {
+			break
+		}
+
+		// extend the hunk while changes are close to each other
+		last := first
+		for i := first; i < len(ops); i++ {
+			if ops[i].kind != ' ' {
+				last = i
+			} else if i-last > 2*diffContextLines {
+				break
+			}
+		}
+
+		hunkStart := first - diffContextLines
+		if hunkStart < start {
+			hunkStart = start
+		}
+		hunkEnd := last + diffContextLines + 1
+		if hunkEnd > len(ops) {
+			hunkEnd = len(ops)
+		}
+
+		fromLine, toLine := 1, 1
+		for _, op := range ops[:hunkStart] {
+			if op.kind != '+' {
+				fromLine++
+			}
+			if op.kind != '-' {
+				toLine++
+			}
+		}
+		var fromCount, toCount int
+		for _, op := range ops[hunkStart:hunkEnd] {
+			if op.kind != '+' {
+				fromCount++
+			}
+			if op.kind != '-' {
+				toCount++
+			}
+		}
+		if fromCount == 0 {
+			fromLine--
+		}
+		if toCount == 0 {
+			toLine--
+		}
+
+		fmt.Fprintf(&b, "@@ -%d,%d +%d,%d @@\n", fromLine, fromCount, toLine, toCount)
+		for _, op := range ops[hunkStart:hunkEnd] {
+			writeDiffLine(&b, op.kind, op.line)
+		}
+		start = hunkEnd
+	}
+	return b.String()
+}
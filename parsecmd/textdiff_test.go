@@ -0,0 +1,86 @@
+package parsecmd
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/facebookgo/ensure"
+)
+
+func TestUnifiedDiff(t *testing.T) {
+	t.Parallel()
+	ensure.DeepEqual(t, unifiedDiff("a/x", "b/x", []byte("same\n"), []byte("same\n")), "")
+	ensure.DeepEqual(t,
+		unifiedDiff("a/x", "b/x", []byte("a\nb\nc\n"), []byte("a\nB\nc\nd\n")),
+		`--- a/x
++++ b/x
+@@ -1,3 +1,4 @@
+ a
+-b
++B
+ c
++d
+`)
+	ensure.DeepEqual(t,
+		unifiedDiff("/dev/null", "b/x", nil, []byte("new")),
+		`--- /dev/null
++++ b/x
+@@ -0,0 +1,1 @@
++new
+\ No newline at end of file
+`)
+	ensure.DeepEqual(t,
+		unifiedDiff("a/x", "b/x", []byte{0, 1}, []byte{0, 2}),
+		"Binary files a/x and b/x differ\n")
+}
+
+func TestUnifiedDiffHunks(t *testing.T) {
+	t.Parallel()
+	var from, to []string
+	for i := 1; i <= 20; i++ {
+		from = append(from, fmt.Sprint(i))
+		to = append(to, fmt.Sprint(i))
+	}
+	to[1] = "two"
+	to[17] = "eighteen"
+	ensure.DeepEqual(t,
+		unifiedDiff("a/x", "b/x",
+			[]byte(strings.Join(from, "\n")+"\n"),
+			[]byte(strings.Join(to, "\n")+"\n")),
+		`--- a/x
++++ b/x
+@@ -1,5 +1,5 @@
+ 1
+-2
++two
+ 3
+ 4
+ 5
+@@ -15,6 +15,6 @@
+ 15
+ 16
+ 17
+-18
++eighteen
+ 19
+ 20
+`)
+}
+
+func TestUnifiedDiffTooManyEdits(t *testing.T) {
+	t.Parallel()
+	var from, to bytes.Buffer
+	for i := 0; i < maxDiffEdits; i++ {
+		fmt.Fprintf(&from, "a%d\n", i)
+		fmt.Fprintf(&to, "b%d\n", i)
+	}
+	ensure.DeepEqual(t,
+		unifiedDiff("a/x", "b/x", from.Bytes(), to.Bytes()),
+		fmt.Sprintf("Files a/x and b/x differ in more than %d lines\n", maxDiffEdits))
+
+	// a file added in full is not limited
+	diff := unifiedDiff("/dev/null", "b/x", nil, to.Bytes())
+	ensure.StringContains(t, diff, fmt.Sprintf("@@ -0,0 +1,%d @@\n", maxDiffEdits))
+}
@@ -0,0 +1,125 @@
+package parsecmd
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/facebookgo/stackerr"
+)
+
+const (
+	// maxUploadAttempts bounds how many times a single file is uploaded
+	// before it is reported as failed.
+	maxUploadAttempts = 5
+	uploadBackoffBase = 500 * time.Millisecond
+	uploadBackoffMax  = 16 * time.Second
+)
+
+// errUploadClosed stops the encoder of an upload body closed before it was
+// fully read.
+var errUploadClosed = errors.New("upload body closed")
+
+// uploadBody is the body of a file upload, encoded by a goroutine.
+type uploadBody struct {
+	*io.PipeReader
+	done chan struct{}
+}
+
+// Close stops the encoder and waits for it to exit, so that the content is
+// not read anymore once Close returns.
+func (b *uploadBody) Close() error {
+	b.PipeReader.CloseWithError(errUploadClosed)
+	<-b.done
+	return nil
+}
+
+// encodeUpload streams the JSON body uploading content as the file with the
+// given name. The content is base64 encoded as it is read, like encoding/json
+// does for a []byte, so that memory use does not depend on the file size.
+func encodeUpload(name string, content io.Reader) *uploadBody {
+	r, w := io.Pipe()
+	b := &uploadBody{PipeReader: r, done: make(chan struct{})}
+	go func() {
+		defer close(b.done)
+		w.CloseWithError(writeUpload(w, name, content))
+	}()
+	return b
+}
+
+func writeUpload(w io.Writer, name string, content io.Reader) error {
+	encodedName, err := json.Marshal(name)
+	if err != nil {
+		return err
+	}
+	if _, err := fmt.Fprintf(w, `{"name":%s,"content":"`, encodedName); err != nil {
+		return err
+	}
+	encoder := base64.NewEncoder(base64.StdEncoding, w)
+	if _, err := io.Copy(encoder, content); err != nil {
+		return err
+	}
+	if err := encoder.Close(); err != nil {
+		return err
+	}
+	_, err = io.WriteString(w, `"}`)
+	return err
+}
+
+// uploadBackoff returns how long to wait before the given attempt to upload a
+// file again: exponential in the number of attempts, with jitter so that
+// files failing together are not retried together.
+func uploadBackoff(attempt int) time.Duration {
+	backoff := uploadBackoffBase << uint(attempt-1)
+	if backoff <= 0 || backoff > uploadBackoffMax {
+		backoff = uploadBackoffMax
+	}
+	return backoff/2 + time.Duration(rand.Int63n(int64(backoff/2)))
+}
+
+// isTransientUploadError reports whether uploading the file again may
+// succeed, given the HTTP status of the response if there was one. Local files
+// that cannot be read and requests the server rejects with a 4xx status are
+// not retried, whatever the format of the error body.
+func isTransientUploadError(err error, status int) bool {
+	if status >= 400 && status < 500 {
+		return false
+	}
+	return !stackerr.HasUnderlying(err, stackerr.MatcherFunc(func(err error) bool {
+		_, ok := err.(*os.PathError)
+		return ok
+	}))
+}
+
+// uploadFailure describes a file that could not be uploaded.
+type uploadFailure struct {
+	Name    string
+	Message string
+}
+
+// uploadError lists the files that could not be uploaded after retrying.
+type uploadError struct {
+	Failures []uploadFailure
+}
+
+func (u *uploadError) Error() string {
+	sort.Sort(byUploadFailureName(u.Failures))
+	lines := []string{fmt.Sprintf("Failed to upload %d files:", len(u.Failures))}
+	for _, f := range u.Failures {
+		lines = append(lines, fmt.Sprintf("  %s: %s", f.Name, f.Message))
+	}
+	return strings.Join(lines, "\n")
+}
+
+type byUploadFailureName []uploadFailure
+
+func (b byUploadFailureName) Len() int           { return len(b) }
+func (b byUploadFailureName) Swap(i, j int)      { b[i], b[j] = b[j], b[i] }
+func (b byUploadFailureName) Less(i, j int) bool { return b[i].Name < b[j].Name }
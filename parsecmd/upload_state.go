@@ -0,0 +1,123 @@
+package parsecmd
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/ParsePlatform/parse-cli/parsecli"
+	"github.com/facebookgo/stackerr"
+)
+
+const uploadStateDir = "uploads"
+
+// uploadRecord is a file uploaded by a deploy that has not created a release
+// yet.
+type uploadRecord struct {
+	Target   string `json:"target"`
+	Name     string `json:"name"`
+	Checksum string `json:"checksum"`
+	Version  string `json:"version"`
+}
+
+// uploadState persists the versions of uploaded files as they arrive, so that
+// a deploy interrupted before creating its release does not upload them again.
+// Records are appended one JSON object per line, the last record of a file
+// wins.
+type uploadState struct {
+	path string
+
+	mutex sync.Mutex
+	file  *os.File
+	err   error
+}
+
+// openUploadState opens the upload state of the given app, and returns the
+// files uploaded by earlier deploys that did not finish.
+func openUploadState(e *parsecli.Env, applicationID string) (*uploadState, *deployInfo, error) {
+	s := &uploadState{
+		path: filepath.Join(e.Root, parsecli.StateDir, uploadStateDir, applicationID+".log"),
+	}
+	uploaded := &deployInfo{
+		Checksums: deployFileData{Cloud: map[string]string{}, Public: map[string]string{}},
+		Versions:  deployFileData{Cloud: map[string]string{}, Public: map[string]string{}},
+	}
+
+	file, err := os.Open(s.path)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, nil, stackerr.Wrap(err)
+	}
+	if err == nil {
+		scanner := bufio.NewScanner(file)
+		for scanner.Scan() {
+			var r uploadRecord
+			// the last line is partial if the deploy was killed while writing it
+			if err := json.Unmarshal(scanner.Bytes(), &r); err != nil {
+				continue
+			}
+			for _, target := range deployTargets {
+				if target.Name == r.Target {
+					target.checksums(uploaded)[r.Name] = r.Checksum
+					target.versions(uploaded)[r.Name] = r.Version
+				}
+			}
+		}
+		err := scanner.Err()
+		file.Close()
+		if err != nil {
+			return nil, nil, stackerr.Wrap(err)
+		}
+	}
+
+	if err := os.MkdirAll(filepath.Dir(s.path), 0755); err != nil {
+		return nil, nil, stackerr.Wrap(err)
+	}
+	s.file, err = os.OpenFile(s.path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0600)
+	if err != nil {
+		return nil, nil, stackerr.Wrap(err)
+	}
+	return s, uploaded, nil
+}
+
+// record saves the version of an uploaded file. Failing to save it only
+// makes a later deploy upload the file again, so the first error is kept to
+// be reported once instead of failing the deploy.
+func (s *uploadState) record(t deployTarget, name, checksum, version string) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if s.err != nil {
+		return
+	}
+	line, err := json.Marshal(&uploadRecord{
+		Target:   t.Name,
+		Name:     name,
+		Checksum: checksum,
+		Version:  version,
+	})
+	if err == nil {
+		_, err = s.file.Write(append(line, '\n'))
+	}
+	s.err = stackerr.Wrap(err)
+}
+
+// clear forgets the recorded files, once they are part of a release.
+func (s *uploadState) clear() error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return stackerr.Wrap(s.file.Truncate(0))
+}
+
+func (s *uploadState) close(e *parsecli.Env) error {
+	if s.err != nil {
+		fmt.Fprintf(
+			e.Err,
+			"Could not save the uploaded files to %s, they will be uploaded again if the deploy is retried:\n%s\n",
+			s.path,
+			parsecli.ErrorString(e, s.err),
+		)
+	}
+	return stackerr.Wrap(s.file.Close())
+}
@@ -0,0 +1,340 @@
+package parsecmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/ParsePlatform/parse-cli/parsecli"
+	"github.com/facebookgo/ensure"
+)
+
+// newFlakyUploadHarness serves uploads with the given status and body for the
+// first failures attempts to upload each file, and counts the attempts.
+func newFlakyUploadHarness(t testing.TB, status int, body string, failures map[string]int) (*parsecli.Harness, map[string]int) {
+	h := setupForDeploy(t, &deployInfo{})
+
+	var mutex sync.Mutex
+	attempts := make(map[string]int)
+	ht := h.Env.ParseAPIClient.APIClient.Transport
+	h.Env.ParseAPIClient.APIClient.Transport = parsecli.TransportFunc(
+		func(r *http.Request) (*http.Response, error) {
+			if r.URL.Path != "/1/scripts" && r.URL.Path != "/1/hosted_files" {
+				return ht.RoundTrip(r)
+			}
+			var file struct {
+				Name string `json:"name"`
+			}
+			ensure.Nil(t, json.NewDecoder(r.Body).Decode(&file))
+
+			mutex.Lock()
+			attempts[file.Name]++
+			n := attempts[file.Name]
+			mutex.Unlock()
+
+			if n <= failures[file.Name] {
+				return &http.Response{
+					StatusCode: status,
+					Body:       ioutil.NopCloser(strings.NewReader(body)),
+				}, nil
+			}
+			return &http.Response{
+				StatusCode: http.StatusOK,
+				Body:       ioutil.NopCloser(strings.NewReader(`{"version":"f2"}`)),
+			}, nil
+		})
+	return h, attempts
+}
+
+func noUploadWait(int) time.Duration { return 0 }
+
+func TestUploadRetriesTransientErrors(t *testing.T) {
+	t.Parallel()
+	h, attempts := newFlakyUploadHarness(t, http.StatusServiceUnavailable, "unavailable",
+		map[string]int{"main.js": 2})
+	defer h.Stop()
+
+	d := deployCmd{uploadWait: noUploadWait}
+	res, err := d.deploy("latest", nil, false, h.Env)
+	ensure.Nil(t, err)
+	ensure.DeepEqual(t, res.Versions.Cloud, map[string]string{"main.js": "f2"})
+	ensure.DeepEqual(t, attempts, map[string]int{"main.js": 3, "index.html": 1})
+}
+
+func TestUploadReportsPermanentFailures(t *testing.T) {
+	t.Parallel()
+	h, attempts := newFlakyUploadHarness(t, http.StatusServiceUnavailable, "unavailable",
+		map[string]int{"main.js": maxUploadAttempts})
+	defer h.Stop()
+
+	d := deployCmd{uploadWait: noUploadWait}
+	_, err := d.deploy("latest", nil, false, h.Env)
+	ensure.Err(t, err, regexp.MustCompile(`^Failed to upload 1 files:
+  cloud/main.js: .*status=503`))
+	ensure.DeepEqual(t, attempts, map[string]int{"main.js": maxUploadAttempts, "index.html": 1})
+
+	// the retried deploy only uploads the file that failed
+	res, err := d.deploy("latest", nil, false, h.Env)
+	ensure.Nil(t, err)
+	ensure.DeepEqual(t, attempts, map[string]int{"main.js": maxUploadAttempts + 1, "index.html": 1})
+	ensure.DeepEqual(t, res.Versions, deployFileData{
+		Cloud:  map[string]string{"main.js": "f2"},
+		Public: map[string]string{"index.html": "f2"},
+	})
+}
+
+func TestUploadDoesNotRetryClientErrors(t *testing.T) {
+	t.Parallel()
+	for _, body := range []string{"bad request", `{"code":141,"error":"syntax error"}`} {
+		h, attempts := newFlakyUploadHarness(t, http.StatusBadRequest, body,
+			map[string]int{"main.js": 1})
+		d := deployCmd{uploadWait: noUploadWait}
+		_, err := d.deploy("latest", nil, false, h.Env)
+		ensure.Err(t, err, regexp.MustCompile("cloud/main.js: "))
+		ensure.DeepEqual(t, attempts["main.js"], 1, body)
+		h.Stop()
+	}
+}
+
+func TestUploadBackoff(t *testing.T) {
+	t.Parallel()
+	for attempt := 1; attempt < 20; attempt++ {
+		backoff := uploadBackoffBase << uint(attempt-1)
+		if backoff <= 0 || backoff > uploadBackoffMax {
+			backoff = uploadBackoffMax
+		}
+		wait := uploadBackoff(attempt)
+		ensure.True(t, wait >= backoff/2 && wait < backoff, attempt, wait)
+	}
+}
+
+// newResumedDeploy returns a deploy command resuming the uploads persisted in
+// the project, like deploy does for an app.
+func newResumedDeploy(t testing.TB, h *parsecli.Harness) (*deployCmd, *deployInfo) {
+	state, uploaded, err := openUploadState(h.Env, "app")
+	ensure.Nil(t, err)
+	return &deployCmd{uploadWait: noUploadWait, state: state, uploaded: uploaded}, uploaded
+}
+
+func TestDeployResumesPersistedUploads(t *testing.T) {
+	t.Parallel()
+	h, attempts := newFlakyUploadHarness(t, http.StatusServiceUnavailable, "unavailable",
+		map[string]int{"main.js": maxUploadAttempts})
+	defer h.Stop()
+
+	d, _ := newResumedDeploy(t, h)
+	_, err := d.deploy("latest", nil, false, h.Env)
+	ensure.NotNil(t, err)
+	ensure.Nil(t, d.state.close(h.Env))
+
+	d, uploaded := newResumedDeploy(t, h)
+	ensure.DeepEqual(t, uploaded.Versions, deployFileData{
+		Cloud:  map[string]string{},
+		Public: map[string]string{"index.html": "f2"},
+	})
+	res, err := d.deploy("latest", nil, false, h.Env)
+	ensure.Nil(t, err)
+	ensure.Nil(t, d.state.close(h.Env))
+	ensure.DeepEqual(t, attempts, map[string]int{"main.js": maxUploadAttempts + 1, "index.html": 1})
+	ensure.DeepEqual(t, res.Versions, deployFileData{
+		Cloud:  map[string]string{"main.js": "f2"},
+		Public: map[string]string{"index.html": "f2"},
+	})
+
+	// the uploads are forgotten once the release is created
+	d, uploaded = newResumedDeploy(t, h)
+	defer d.state.close(h.Env)
+	ensure.DeepEqual(t, uploaded.Versions, deployFileData{
+		Cloud:  map[string]string{},
+		Public: map[string]string{},
+	})
+}
+
+func TestDeployReuploadsChangedPersistedFiles(t *testing.T) {
+	t.Parallel()
+	h, attempts := newFlakyUploadHarness(t, http.StatusServiceUnavailable, "unavailable",
+		map[string]int{"main.js": maxUploadAttempts})
+	defer h.Stop()
+
+	d, _ := newResumedDeploy(t, h)
+	_, err := d.deploy("latest", nil, false, h.Env)
+	ensure.NotNil(t, err)
+	ensure.Nil(t, d.state.close(h.Env))
+
+	ensure.Nil(t, ioutil.WriteFile(
+		filepath.Join(h.Env.Root, parsecli.HostingDir, "index.html"),
+		[]byte("changed"),
+		0600,
+	))
+	// a deploy killed while writing its state leaves a partial record
+	f, err := os.OpenFile(filepath.Join(h.Env.Root, parsecli.StateDir, uploadStateDir, "app.log"),
+		os.O_WRONLY|os.O_APPEND, 0600)
+	ensure.Nil(t, err)
+	_, err = f.WriteString(`{"target":"public","na`)
+	ensure.Nil(t, err)
+	ensure.Nil(t, f.Close())
+
+	d, _ = newResumedDeploy(t, h)
+	defer d.state.close(h.Env)
+	_, err = d.deploy("latest", nil, false, h.Env)
+	ensure.Nil(t, err)
+	ensure.DeepEqual(t, attempts, map[string]int{"main.js": maxUploadAttempts + 1, "index.html": 2})
+}
+
+func TestEncodeUpload(t *testing.T) {
+	t.Parallel()
+	content := bytes.Repeat([]byte{0, 1, 2, 3, 254, 255}, 20000)
+	body := encodeUpload(`dir/"quoted".js`, bytes.NewReader(content))
+	defer body.Close()
+
+	var file struct {
+		Name    string `json:"name"`
+		Content []byte `json:"content"`
+	}
+	ensure.Nil(t, json.NewDecoder(body).Decode(&file))
+	ensure.DeepEqual(t, file.Name, `dir/"quoted".js`)
+	ensure.DeepEqual(t, file.Content, content)
+}
+
+// blockingReader returns a byte once released, after signaling it is read.
+type blockingReader struct {
+	reading, release chan struct{}
+}
+
+func (r *blockingReader) Read(p []byte) (int, error) {
+	close(r.reading)
+	<-r.release
+	p[0] = 'a'
+	return 1, io.EOF
+}
+
+func TestEncodeUploadCloseWaitsForEncoder(t *testing.T) {
+	t.Parallel()
+	content := &blockingReader{reading: make(chan struct{}), release: make(chan struct{})}
+	body := encodeUpload("a.js", content)
+	header := make([]byte, len(`{"name":"a.js","content":"`))
+	_, err := io.ReadFull(body, header)
+	ensure.Nil(t, err)
+	<-content.reading
+
+	closed := make(chan struct{})
+	go func() {
+		body.Close()
+		close(closed)
+	}()
+	select {
+	case <-closed:
+		t.Fatal("closed while the content is read")
+	case <-time.After(20 * time.Millisecond):
+	}
+	close(content.release)
+	select {
+	case <-closed:
+	case <-time.After(5 * time.Second):
+		t.Fatal("not closed")
+	}
+}
+
+func TestUploadConcurrency(t *testing.T) {
+	t.Parallel()
+	h := setupForDeploy(t, &deployInfo{})
+	defer h.Stop()
+	for i := 0; i < 10; i++ {
+		ensure.Nil(t, ioutil.WriteFile(
+			filepath.Join(h.Env.Root, parsecli.CloudDir, fmt.Sprintf("file%d.js", i)),
+			[]byte("content"),
+			0600,
+		))
+	}
+
+	var inFlight, maxInFlight int32
+	ht := h.Env.ParseAPIClient.APIClient.Transport
+	h.Env.ParseAPIClient.APIClient.Transport = parsecli.TransportFunc(
+		func(r *http.Request) (*http.Response, error) {
+			if r.URL.Path == "/1/scripts" {
+				n := atomic.AddInt32(&inFlight, 1)
+				defer atomic.AddInt32(&inFlight, -1)
+				for {
+					max := atomic.LoadInt32(&maxInFlight)
+					if n <= max || atomic.CompareAndSwapInt32(&maxInFlight, max, n) {
+						break
+					}
+				}
+				time.Sleep(time.Millisecond)
+			}
+			return ht.RoundTrip(r)
+		})
+
+	d := deployCmd{Concurrency: 2}
+	res, err := d.deploy("latest", nil, false, h.Env)
+	ensure.Nil(t, err)
+	ensure.DeepEqual(t, len(res.Versions.Cloud), 11)
+	ensure.True(t, atomic.LoadInt32(&maxInFlight) <= 2, maxInFlight)
+}
+
+func TestUploadProgress(t *testing.T) {
+	t.Parallel()
+	h := setupForDeploy(t, &deployInfo{})
+	defer h.Stop()
+
+	var out bytes.Buffer
+	h.Env.Progress = &parsecli.ProgressReporter{Out: &out, Clock: h.Clock}
+
+	var d deployCmd
+	_, err := d.deploy("latest", nil, false, h.Env)
+	ensure.Nil(t, err)
+	ensure.StringContains(t, out.String(), "Uploading scripts and hosting: 2/2 files")
+}
+
+func TestUploadScriptsFirst(t *testing.T) {
+	t.Parallel()
+	h := setupForDeploy(t, &deployInfo{})
+	defer h.Stop()
+	for i := 0; i < 5; i++ {
+		for _, dir := range []string{parsecli.CloudDir, parsecli.HostingDir} {
+			ensure.Nil(t, ioutil.WriteFile(
+				filepath.Join(h.Env.Root, dir, fmt.Sprintf("file%d.js", i)),
+				[]byte("content"),
+				0600,
+			))
+		}
+	}
+
+	var (
+		mutex sync.Mutex
+		paths []string
+	)
+	ht := h.Env.ParseAPIClient.APIClient.Transport
+	h.Env.ParseAPIClient.APIClient.Transport = parsecli.TransportFunc(
+		func(r *http.Request) (*http.Response, error) {
+			if r.URL.Path == "/1/scripts" || r.URL.Path == "/1/hosted_files" {
+				mutex.Lock()
+				paths = append(paths, r.URL.Path)
+				mutex.Unlock()
+			}
+			return ht.RoundTrip(r)
+		})
+
+	d := deployCmd{Concurrency: 1}
+	_, err := d.deploy("latest", nil, false, h.Env)
+	ensure.Nil(t, err)
+	ensure.DeepEqual(t, len(paths), 12)
+	for i, path := range paths {
+		expected := "/1/scripts"
+		if i >= 6 {
+			expected = "/1/hosted_files"
+		}
+		ensure.DeepEqual(t, path, expected, i)
+	}
+}
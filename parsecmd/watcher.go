@@ -0,0 +1,81 @@
+package parsecmd
+
+import (
+	"errors"
+	"time"
+
+	"github.com/facebookgo/clock"
+)
+
+// watchQuiet is how long files must stay unchanged after a change before
+// develop deploys them, so that a burst of saves results in a single deploy.
+const watchQuiet = 300 * time.Millisecond
+
+// errWatchUnsupported is returned by newWatcher on platforms without file
+// change notifications.
+var errWatchUnsupported = errors.New("file change notifications are not supported on this platform")
+
+// watcher notifies changes to the files of some directory trees and to some
+// single files.
+type watcher interface {
+	// Changes receives a value after files changed. Changes happening before
+	// the value is received are coalesced. It is closed when the watcher is
+	// closed or stops watching.
+	Changes() <-chan struct{}
+	// Err returns why the watcher stopped watching before being closed.
+	Err() error
+	Close() error
+}
+
+// debounce sends a value on the returned channel once no value was received
+// from changes for quiet, and when initial is set, right away. The returned
+// channel is closed once changes is closed.
+func debounce(c clock.Clock, changes <-chan struct{}, quiet time.Duration, initial bool, done <-chan struct{}) <-chan struct{} {
+	out := make(chan struct{}, 1)
+	if initial {
+		out <- struct{}{}
+	}
+	go func() {
+		defer close(out)
+		for {
+			select {
+			case _, ok := <-changes:
+				if !ok {
+					return
+				}
+			case <-done:
+				return
+			}
+			// a ticker instead of a timer reset on every change, as timers
+			// cannot be reset
+			last := c.Now()
+			ticker := c.Ticker(quiet / 4)
+			for settled := false; !settled; {
+				select {
+				case _, ok := <-changes:
+					if ok {
+						last = c.Now()
+					} else {
+						// deliver the pending change before closing out
+						settled = true
+						changes = nil
+					}
+				case now := <-ticker.C:
+					settled = now.Sub(last) >= quiet
+				case <-done:
+					ticker.Stop()
+					return
+				}
+			}
+			ticker.Stop()
+			select {
+			case out <- struct{}{}:
+			default:
+			}
+			if changes == nil {
+				return
+			}
+		}
+	}()
+	return out
+}
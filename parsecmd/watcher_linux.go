@@ -0,0 +1,300 @@
+package parsecmd
+
+import (
+	"os"
+	"path/filepath"
+	"sync"
+	"syscall"
+	"unsafe"
+
+	"github.com/ParsePlatform/parse-cli/parsecli"
+	"github.com/facebookgo/stackerr"
+)
+
+const inotifyMask = syscall.IN_CREATE | syscall.IN_DELETE | syscall.IN_MODIFY |
+	syscall.IN_CLOSE_WRITE | syscall.IN_ATTRIB | syscall.IN_MOVED_FROM |
+	syscall.IN_MOVED_TO | syscall.IN_DELETE_SELF | syscall.IN_ONLYDIR
+
+// inotifyWatcher watches directory trees and single files with inotify,
+// which needs a watch on every directory of the trees, and on the parent
+// directory of the files.
+type inotifyWatcher struct {
+	fd      int    // the inotify instance
+	epoll   int    // waits for events, or for Close
+	stop    [2]int // pipe whose write end is closed by Close
+	changes chan struct{}
+
+	mutex  sync.Mutex
+	dirs   map[int32]string           // watched directories by watch descriptor
+	trees  map[string]bool            // directories whose whole content is watched
+	names  map[string]map[string]bool // watched names by parent directory
+	closed bool
+	err    error
+}
+
+// newWatcher watches the trees under dirs and the files, which do not need to
+// exist yet as long as their parent directory exists.
+func newWatcher(dirs, files []string) (watcher, error) {
+	fd, err := syscall.InotifyInit1(syscall.IN_CLOEXEC)
+	if err != nil {
+		return nil, stackerr.Wrap(err)
+	}
+	w := &inotifyWatcher{
+		fd:      fd,
+		changes: make(chan struct{}, 1),
+		dirs:    make(map[int32]string),
+		trees:   make(map[string]bool),
+		names:   make(map[string]map[string]bool),
+	}
+	if err := w.addAll(dirs, files); err != nil {
+		syscall.Close(fd)
+		return nil, err
+	}
+	if err := w.initEpoll(); err != nil {
+		syscall.Close(fd)
+		return nil, err
+	}
+	go w.read()
+	return w, nil
+}
+
+// initEpoll sets up the wait for events or for Close, as closing the inotify
+// descriptor does not interrupt a blocked read.
+func (w *inotifyWatcher) initEpoll() error {
+	if err := syscall.Pipe2(w.stop[:], syscall.O_CLOEXEC); err != nil {
+		return stackerr.Wrap(err)
+	}
+	epoll, err := syscall.EpollCreate1(syscall.EPOLL_CLOEXEC)
+	if err != nil {
+		closeFds(w.stop[0], w.stop[1])
+		return stackerr.Wrap(err)
+	}
+	for _, fd := range []int{w.fd, w.stop[0]} {
+		event := syscall.EpollEvent{Events: syscall.EPOLLIN, Fd: int32(fd)}
+		if err := syscall.EpollCtl(epoll, syscall.EPOLL_CTL_ADD, fd, &event); err != nil {
+			closeFds(epoll, w.stop[0], w.stop[1])
+			return stackerr.Wrap(err)
+		}
+	}
+	w.epoll = epoll
+	return nil
+}
+
+func closeFds(fds ...int) {
+	for _, fd := range fds {
+		syscall.Close(fd)
+	}
+}
+
+func (w *inotifyWatcher) addAll(dirs, files []string) error {
+	// a directory is watched through its parent until it exists
+	for _, path := range append(dirs, files...) {
+		if err := w.addName(path); err != nil {
+			return err
+		}
+	}
+	for _, dir := range dirs {
+		if err := w.addTree(dir); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ignoredDir reports whether changes in a directory never affect deploys.
+func ignoredDir(name string) bool {
+	return name == parsecli.StateDir || name == ".git"
+}
+
+func (w *inotifyWatcher) addWatch(dir string) error {
+	wd, err := syscall.InotifyAddWatch(w.fd, dir, inotifyMask)
+	if err != nil {
+		return stackerr.Newf("Unable to watch %s: %s", dir, err)
+	}
+	w.mutex.Lock()
+	w.dirs[int32(wd)] = dir
+	w.mutex.Unlock()
+	return nil
+}
+
+// addName watches the creation, removal and changes of path.
+func (w *inotifyWatcher) addName(path string) error {
+	dir, name := filepath.Split(path)
+	dir = filepath.Clean(dir)
+	if _, err := os.Stat(dir); os.IsNotExist(err) {
+		return nil
+	}
+	if err := w.addWatch(dir); err != nil {
+		return err
+	}
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	if w.names[dir] == nil {
+		w.names[dir] = make(map[string]bool)
+	}
+	w.names[dir][name] = true
+	return nil
+}
+
+// addTree watches dir and all the directories under it.
+func (w *inotifyWatcher) addTree(dir string) error {
+	return filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) {
+				// not created yet, or removed while walking
+				return nil
+			}
+			return stackerr.Wrap(err)
+		}
+		if !info.IsDir() {
+			return nil
+		}
+		if path != dir && ignoredDir(info.Name()) {
+			return filepath.SkipDir
+		}
+		if err := w.addWatch(path); err != nil {
+			return err
+		}
+		w.mutex.Lock()
+		w.trees[path] = true
+		w.mutex.Unlock()
+		return nil
+	})
+}
+
+// watched returns the directory of a watch descriptor, and whether changes to
+// name in it are watched.
+func (w *inotifyWatcher) watched(wd int32, name string) (string, bool) {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	dir, ok := w.dirs[wd]
+	return dir, ok && (w.trees[dir] || w.names[dir][name])
+}
+
+// wait blocks until events can be read, and returns false once the watcher
+// is closed.
+func (w *inotifyWatcher) wait() (bool, error) {
+	var events [2]syscall.EpollEvent
+	for {
+		n, err := syscall.EpollWait(w.epoll, events[:], -1)
+		if err == syscall.EINTR {
+			continue
+		}
+		if err != nil {
+			return false, stackerr.Wrap(err)
+		}
+		for _, event := range events[:n] {
+			if int(event.Fd) == w.stop[0] {
+				return false, nil
+			}
+		}
+		if n != 0 {
+			return true, nil
+		}
+	}
+}
+
+func (w *inotifyWatcher) read() {
+	defer close(w.changes)
+	defer closeFds(w.fd, w.epoll, w.stop[0])
+	var buf [syscall.SizeofInotifyEvent * 4096]byte
+	for {
+		ok, err := w.wait()
+		if !ok {
+			if err != nil {
+				w.fail(err)
+			}
+			return
+		}
+		n, err := syscall.Read(w.fd, buf[:])
+		if err == syscall.EINTR {
+			continue
+		}
+		if err != nil {
+			w.fail(stackerr.Wrap(err))
+			return
+		}
+		changed := false
+		for offset := 0; offset+syscall.SizeofInotifyEvent <= n; {
+			event := (*syscall.InotifyEvent)(unsafe.Pointer(&buf[offset]))
+			nameBytes := buf[offset+syscall.SizeofInotifyEvent : offset+syscall.SizeofInotifyEvent+int(event.Len)]
+			offset += syscall.SizeofInotifyEvent + int(event.Len)
+			name := string(trimNUL(nameBytes))
+
+			if event.Mask&syscall.IN_Q_OVERFLOW != 0 {
+				// events were dropped, the next deploy rescans all the files
+				changed = true
+				continue
+			}
+			if event.Mask&syscall.IN_IGNORED != 0 {
+				w.mutex.Lock()
+				delete(w.trees, w.dirs[event.Wd])
+				delete(w.dirs, event.Wd)
+				w.mutex.Unlock()
+				continue
+			}
+			isDir := event.Mask&syscall.IN_ISDIR != 0
+			if isDir && ignoredDir(name) {
+				continue
+			}
+			dir, change := w.watched(event.Wd, name)
+			if !change {
+				continue
+			}
+			if isDir && event.Mask&(syscall.IN_CREATE|syscall.IN_MOVED_TO) != 0 {
+				// files created in the new directory before it is watched
+				// are picked up by the deploy triggered by this change
+				if err := w.addTree(filepath.Join(dir, name)); err != nil {
+					w.fail(err)
+					return
+				}
+			}
+			changed = true
+		}
+		if changed {
+			select {
+			case w.changes <- struct{}{}:
+			default:
+			}
+		}
+	}
+}
+
+// fail records why the watcher stopped watching, unless it was closed.
+func (w *inotifyWatcher) fail(err error) {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	if !w.closed {
+		w.err = err
+	}
+}
+
+func trimNUL(b []byte) []byte {
+	for i, c := range b {
+		if c == 0 {
+			return b[:i]
+		}
+	}
+	return b
+}
+
+func (w *inotifyWatcher) Changes() <-chan struct{} {
+	return w.changes
+}
+
+func (w *inotifyWatcher) Err() error {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	return w.err
+}
+
+func (w *inotifyWatcher) Close() error {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	if w.closed {
+		return nil
+	}
+	w.closed = true
+	// read sees the end of the pipe, and closes the other descriptors
+	return stackerr.Wrap(syscall.Close(w.stop[1]))
+}
@@ -0,0 +1,91 @@
+package parsecmd
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/ParsePlatform/parse-cli/parsecli"
+	"github.com/facebookgo/ensure"
+)
+
+func waitChange(t testing.TB, w watcher) {
+	select {
+	case <-w.Changes():
+	case <-time.After(5 * time.Second):
+		t.Fatal("change not notified")
+	}
+}
+
+func waitNoChange(t testing.TB, w watcher) {
+	select {
+	case <-w.Changes():
+		t.Fatal("unexpected change")
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+func TestInotifyWatcher(t *testing.T) {
+	t.Parallel()
+	root, err := ioutil.TempDir("", "watcher")
+	ensure.Nil(t, err)
+	defer os.RemoveAll(root)
+	cloud := filepath.Join(root, parsecli.CloudDir)
+	ensure.Nil(t, os.MkdirAll(filepath.Join(cloud, ".git"), 0755))
+	ensure.Nil(t, os.MkdirAll(filepath.Join(root, "node_modules"), 0755))
+
+	w, err := newWatcher(
+		[]string{cloud, filepath.Join(root, parsecli.HostingDir)},
+		[]string{filepath.Join(root, parsecli.ParseLocal)},
+	)
+	ensure.Nil(t, err)
+
+	ensure.Nil(t, ioutil.WriteFile(filepath.Join(cloud, "main.js"), []byte("1"), 0600))
+	waitChange(t, w)
+
+	// files in new directories are watched
+	dir := filepath.Join(cloud, "lib")
+	ensure.Nil(t, os.MkdirAll(dir, 0755))
+	waitChange(t, w)
+	time.Sleep(10 * time.Millisecond)
+	for len(w.Changes()) != 0 {
+		<-w.Changes()
+	}
+	ensure.Nil(t, ioutil.WriteFile(filepath.Join(dir, "lib.js"), []byte("1"), 0600))
+	waitChange(t, w)
+
+	// and so are source directories created later
+	public := filepath.Join(root, parsecli.HostingDir)
+	ensure.Nil(t, os.Mkdir(public, 0755))
+	waitChange(t, w)
+	time.Sleep(10 * time.Millisecond)
+	for len(w.Changes()) != 0 {
+		<-w.Changes()
+	}
+	ensure.Nil(t, ioutil.WriteFile(filepath.Join(public, "index.html"), []byte("1"), 0600))
+	waitChange(t, w)
+
+	ensure.Nil(t, ioutil.WriteFile(filepath.Join(root, parsecli.ParseLocal), []byte("{}"), 0600))
+	waitChange(t, w)
+
+	// other files are not
+	ensure.Nil(t, ioutil.WriteFile(
+		filepath.Join(cloud, ".git", "index"), []byte("1"), 0600))
+	waitNoChange(t, w)
+	ensure.Nil(t, ioutil.WriteFile(
+		filepath.Join(root, "node_modules", "lib.js"), []byte("1"), 0600))
+	waitNoChange(t, w)
+	ensure.Nil(t, ioutil.WriteFile(filepath.Join(root, "notes.txt"), []byte("1"), 0600))
+	waitNoChange(t, w)
+
+	ensure.Nil(t, w.Close())
+	select {
+	case _, open := <-w.Changes():
+		ensure.False(t, open)
+	case <-time.After(5 * time.Second):
+		t.Fatal("changes not closed")
+	}
+	ensure.Nil(t, w.Err())
+}
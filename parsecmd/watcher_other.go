@@ -0,0 +1,8 @@
+//go:build !linux
+// +build !linux
+
+package parsecmd
+
+func newWatcher(dirs, files []string) (watcher, error) {
+	return nil, errWatchUnsupported
+}
@@ -0,0 +1,76 @@
+package parsecmd
+
+import (
+	"testing"
+	"time"
+
+	"github.com/facebookgo/clock"
+	"github.com/facebookgo/ensure"
+)
+
+// waitDebounced advances the clock until out receives a value, and returns
+// how much the clock was advanced.
+func waitDebounced(t testing.TB, c *clock.Mock, out <-chan struct{}) time.Duration {
+	const step = 10 * time.Millisecond
+	var advanced time.Duration
+	for deadline := time.Now().Add(5 * time.Second); time.Now().Before(deadline); {
+		select {
+		case <-out:
+			return advanced
+		case <-time.After(time.Millisecond):
+		}
+		c.Add(step)
+		advanced += step
+	}
+	t.Fatal("no debounced change")
+	return 0
+}
+
+func TestDebounce(t *testing.T) {
+	t.Parallel()
+	c := clock.NewMock()
+	changes := make(chan struct{})
+	done := make(chan struct{})
+	defer close(done)
+
+	out := debounce(c, changes, watchQuiet, true, done)
+	<-out // initial
+
+	for i := 0; i < 5; i++ {
+		changes <- struct{}{}
+	}
+	ensure.True(t, waitDebounced(t, c, out) >= watchQuiet)
+
+	// the burst resulted in a single value
+	c.Add(time.Minute)
+	select {
+	case <-out:
+		t.Fatal("unexpected debounced change")
+	case <-time.After(10 * time.Millisecond):
+	}
+
+	changes <- struct{}{}
+	ensure.True(t, waitDebounced(t, c, out) >= watchQuiet)
+}
+
+func TestDebounceClosed(t *testing.T) {
+	t.Parallel()
+	c := clock.NewMock()
+	changes := make(chan struct{})
+	done := make(chan struct{})
+	defer close(done)
+
+	out := debounce(c, changes, watchQuiet, false, done)
+	changes <- struct{}{}
+	close(changes)
+
+	// the pending change is delivered before out is closed
+	for _, open := range []bool{true, false} {
+		select {
+		case _, ok := <-out:
+			ensure.DeepEqual(t, ok, open)
+		case <-time.After(5 * time.Second):
+			t.Fatal("out not closed")
+		}
+	}
+}
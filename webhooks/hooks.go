@@ -83,6 +83,7 @@ func checkTriggerName(s string) error {
 type Hooks struct {
 	HooksStrict    bool
 	BaseURL        string
+	Confirmed      bool
 	baseWebhookURL *url.URL
 }
 
@@ -350,6 +351,16 @@ func (h *Hooks) HooksCmd(e *parsecli.Env, ctx *parsecli.Context, args []string)
 	if err := h.parseBaseURL(e); err != nil {
 		return err
 	}
+	if len(args) == 0 && !h.Confirmed && ctx.AppConfig != nil && ctx.AppConfig.IsProtected() {
+		// the confirmation would be read from the config on stdin
+		return stackerr.Newf(
+			"Please provide a webhooks config file, or use --%s, to configure a protected app.",
+			parsecli.ConfirmProtectedFlag,
+		)
+	}
+	if err := parsecli.ConfirmProtectedApp(e, ctx, "webhooks configuration", h.Confirmed); err != nil {
+		return err
+	}
 
 	reader := e.In
 	if len(args) == 1 {